@@ -0,0 +1,28 @@
+// package config 包含应用程序配置的加载和管理功能
+package config
+
+// defaultConfig 返回加载配置文件前的起始值，字段均为开箱即可运行于本地开发环境的合理默认值
+// LoadConfig以此为基础依次叠加YAML文件、Profile覆盖文件、环境变量（命令行参数由调用方在LoadConfig之后叠加），
+// 因此这里只需要给出"YAML文件中未出现该字段时应当怎样"的默认值，而不是生产环境的推荐值
+func defaultConfig() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			DBName:  "knowledge_base",
+			SSLMode: "disable",
+		},
+		Minio: MinioConfig{
+			Endpoint:   "localhost:9000",
+			BucketName: "knowledge-base",
+			UseSSL:     false,
+		},
+		Server: ServerConfig{
+			Port: ":8080",
+		},
+		Security: SecurityConfig{
+			PasswordHashAlgo: "bcrypt",
+		},
+	}
+}
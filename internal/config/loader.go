@@ -2,53 +2,77 @@
 package config
 
 import (
-	"io/ioutil" // I/O实用工具函数
-	"os"        // 操作系统功能接口
-	"strconv"   // 字符串和基本数据类型之间的转换
-	"strings"   // 字符串操作函数
+	"flag"          // 命令行参数解析
+	"os"            // 操作系统功能接口
+	"path/filepath" // 用于根据基础配置文件路径计算profile覆盖文件路径
+	"strconv"       // 字符串和基本数据类型之间的转换
+	"strings"       // 字符串操作函数
 
 	"gopkg.in/yaml.v2" // YAML格式的编码和解码，这个项目中主要用来处理yaml格式配置的中间件服务配置信息
 )
 
-// 此处LoadConfig的逻辑流程如下：
-//   - 首先从指定的YAML配置文件加载默认配置
-//   - 然后检查环境变量，如果存在相应的环境变量，则用其值覆盖配置文件中的值
-//   - *Config: 加载并可能被环境变量覆盖后的配置对象
-//   - error: 如果加载过程中发生错误，返回相应的错误信息
+// profileEnvVar 用于选择在基础配置文件之上叠加哪个profile覆盖文件，如"dev"/"prod"
+const profileEnvVar = "KNOWLEDGE_CONFIG_PROFILE"
 
+// 此处LoadConfig的逻辑流程如下，各层级按优先级从低到高依次叠加（后一层级覆盖前一层级同名字段）：
+//  1. defaultConfig中给出的硬编码合理默认值
+//  2. path指向的YAML配置文件
+//  3. KNOWLEDGE_CONFIG_PROFILE指定时，同目录下config.<profile>.yaml覆盖文件
+//  4. 环境变量（overrideFromEnv，向后兼容原有行为）
+//
+// 命令行参数（第5层，ParseFlags/ApplyOverrides）不在这里处理：LoadConfig会在配置热重载时
+// 被反复调用，而命令行参数在进程生命周期内不会变化，由调用方（如config.Manager）在LoadConfig
+// 之后自行叠加一次即可，不需要每次重载都重新解析
+//   - path: YAML配置文件路径
+//   - *Config: 按上述优先级合并后的配置对象
+//   - error: 如果读取或解析过程中发生错误，返回相应的错误信息
 func LoadConfig(path string) (*Config, error) {
-	// 首先从配置文件加载默认配置
-	config, err := loadFromFile(path)
-	if err != nil {
+	// 以硬编码默认值为起点，YAML中缺失的字段将保留这里的默认值
+	config := defaultConfig()
+
+	if err := mergeFromFile(config, path); err != nil {
 		return nil, err
 	}
 
+	// Profile覆盖文件是可选的：未设置KNOWLEDGE_CONFIG_PROFILE时跳过；
+	// 设置了但对应文件不存在时也不视为致命错误，直接跳过且不输出提示，因为不少部署场景下
+	// 基础配置文件本身已经足够，profile覆盖文件是可选的锦上添花
+	config.Profile = os.Getenv(profileEnvVar)
+	if config.Profile != "" {
+		profilePath := profileConfigPath(path, config.Profile)
+		if _, err := os.Stat(profilePath); err == nil {
+			if err := mergeFromFile(config, profilePath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// 然后从环境变量读取变量覆盖配置
 	overrideFromEnv(config)
 
 	return config, nil
 }
 
-// 此处loadFromFile的逻辑流程如下：
-//   - 读取指定路径的YAML配置文件
-//   - 将YAML内容解析为Config结构体
-//   - *Config: 从文件加载的配置对象
-//   - error: 如果读取或解析过程中发生错误，返回相应的错误信息
-func loadFromFile(path string) (*Config, error) {
-	// 读取配置文件内容
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
+// profileConfigPath 根据基础配置文件路径和profile名称计算覆盖文件路径
+// 例如base为"./config.yaml"、profile为"dev"时，返回"./config.dev.yaml"
+func profileConfigPath(base, profile string) string {
+	dir := filepath.Dir(base)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(filepath.Base(base), ext)
+	return filepath.Join(dir, name+"."+profile+ext)
+}
 
-	// 将YAML内容解析为Config结构体
-	var config Config
-	err = yaml.Unmarshal(data, &config)
+// mergeFromFile 读取指定路径的YAML配置文件，并将其内容解析合并到已有的config上
+// 传入的config通常已经带有默认值或更低优先级层级叠加后的值，YAML中未出现的字段会保留原值不变
+//   - config: 待合并写入的配置对象
+//   - path: YAML配置文件路径
+//   - error: 如果读取或解析过程中发生错误，返回相应的错误信息
+func mergeFromFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return &config, nil
+	return yaml.Unmarshal(data, config)
 }
 
 // 此处overrideFromEnv的逻辑流程如下：
@@ -116,3 +140,53 @@ func overrideFromEnv(config *Config) {
 		config.Server.Port = val
 	}
 }
+
+// Overrides 描述可以通过命令行参数覆盖的配置项，零值表示调用方未显式指定、不生效
+type Overrides struct {
+	DBHost        string
+	DBPort        int
+	MinioEndpoint string
+	ServerPort    string
+}
+
+// ParseFlags 使用独立的flag.FlagSet解析args（通常为os.Args[1:]）中的配置覆盖项，
+// 返回-config指定的配置文件路径（未指定时为"./config.yaml"）及其余覆盖项
+// 使用独立的FlagSet而非全局flag.CommandLine，避免重复调用（如测试中）时因重复定义标志而panic
+//   - args: 命令行参数，不包含程序名本身
+//   - configPath: -config标志指定的YAML配置文件路径
+//   - overrides: 其余命令行参数对应的覆盖项，未指定的字段保持零值
+//   - error: 如果参数解析失败，返回相应的错误信息
+func ParseFlags(args []string) (configPath string, overrides Overrides, err error) {
+	fs := flag.NewFlagSet("knowledge", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "./config.yaml", "YAML配置文件路径")
+	fs.StringVar(&overrides.DBHost, "db-host", "", "覆盖数据库主机地址")
+	fs.IntVar(&overrides.DBPort, "db-port", 0, "覆盖数据库端口")
+	fs.StringVar(&overrides.MinioEndpoint, "minio-endpoint", "", "覆盖MinIO服务端点地址")
+	fs.StringVar(&overrides.ServerPort, "server-port", "", "覆盖服务器监听端口")
+
+	if err = fs.Parse(args); err != nil {
+		return "", Overrides{}, err
+	}
+	return configPath, overrides, nil
+}
+
+// ApplyOverrides 将ParseFlags解析得到的命令行覆盖项应用到配置对象上，优先级高于配置文件和环境变量
+// 未指定（零值）的字段保持config中原有的值不变
+func ApplyOverrides(config *Config, o Overrides) {
+	if o.DBHost != "" {
+		config.Database.Host = o.DBHost
+	}
+	if o.DBPort != 0 {
+		config.Database.Port = o.DBPort
+	}
+	if o.MinioEndpoint != "" {
+		config.Minio.Endpoint = o.MinioEndpoint
+	}
+	if o.ServerPort != "" {
+		port := o.ServerPort
+		if !strings.HasPrefix(port, ":") {
+			port = ":" + port
+		}
+		config.Server.Port = port
+	}
+}
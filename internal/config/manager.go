@@ -0,0 +1,181 @@
+// package config 包含应用程序配置的加载和管理功能
+package config
+
+import (
+	"context"       // 用于控制监听循环的生命周期
+	"fmt"           // 格式化输出
+	"log"           // 日志记录
+	"os"            // 用于接收SIGHUP信号
+	"os/signal"     // 信号通知
+	"path/filepath" // 用于定位配置文件所在目录及文件名
+	"reflect"       // 用于对比重载前后的子配置是否发生变化
+	"sync"          // 用于保护配置快照的并发读写
+	"syscall"       // SIGHUP信号常量
+
+	"github.com/fsnotify/fsnotify" // 文件系统变更通知
+)
+
+// DatabaseChangeFunc 是数据库配置发生变化时被调用的订阅者
+type DatabaseChangeFunc func(old, new DatabaseConfig)
+
+// MinioChangeFunc 是MinIO配置发生变化时被调用的订阅者
+type MinioChangeFunc func(old, new MinioConfig)
+
+// ServerChangeFunc 是HTTP服务配置发生变化时被调用的订阅者
+type ServerChangeFunc func(old, new ServerConfig)
+
+// Manager 监听配置文件变化（fsnotify）及SIGHUP信号，重新加载配置后对比子配置的差异，
+// 仅对发生变化的部分通知相应的订阅者。Snapshot()返回的*Config在每次重载时整体替换，
+// 调用方任意时刻读到的都是某一次加载的完整配置，不会出现一半新一半旧的撕裂读
+type Manager struct {
+	path      string    // 配置文件路径，同时也是LoadConfig的加载来源
+	overrides Overrides // 启动时由命令行参数解析得到的覆盖项，每次(重新)加载后都会重新叠加一遍
+
+	mu  sync.RWMutex
+	cfg *Config
+
+	dbSubs     []DatabaseChangeFunc
+	minioSubs  []MinioChangeFunc
+	serverSubs []ServerChangeFunc
+}
+
+// NewManager 加载一次配置文件并返回一个尚未开始监听的Manager，调用方应在注册完订阅者后调用Start
+//   - path: YAML配置文件路径
+//   - overrides: 命令行参数覆盖项（见ParseFlags），每次加载（包括热重载）后都会重新叠加在最上层，
+//     确保进程启动时指定的命令行覆盖不会被之后的文件/环境变量热重载覆盖掉
+//   - *Manager: 新创建的Manager实例
+//   - error: 如果首次加载失败，返回相应的错误信息
+func NewManager(path string, overrides Overrides) (*Manager, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	ApplyOverrides(cfg, overrides)
+	return &Manager{path: path, overrides: overrides, cfg: cfg}, nil
+}
+
+// Snapshot 返回当前配置的一份快照，调用方可以安全地持有并读取，不受后续重载影响
+//   - *Config: 最近一次成功加载的配置
+func (m *Manager) Snapshot() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cfg
+}
+
+// OnDatabaseChange 注册一个数据库配置变更时调用的订阅者
+func (m *Manager) OnDatabaseChange(fn DatabaseChangeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dbSubs = append(m.dbSubs, fn)
+}
+
+// OnMinioChange 注册一个MinIO配置变更时调用的订阅者
+func (m *Manager) OnMinioChange(fn MinioChangeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.minioSubs = append(m.minioSubs, fn)
+}
+
+// OnServerChange 注册一个HTTP服务配置变更时调用的订阅者
+func (m *Manager) OnServerChange(fn ServerChangeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serverSubs = append(m.serverSubs, fn)
+}
+
+// Start 开始监听配置文件变化以及SIGHUP信号，两者都会触发重新加载
+// 监听的是文件所在目录而非文件本身：许多编辑器及`kubectl apply`一类的工具以"写临时文件+rename"的方式
+// 更新文件，直接监听文件句柄会在rename后失效，改为监听目录再按文件名过滤可以避免这个问题
+//   - ctx: 用于停止监听循环，ctx结束后监听goroutine随之退出
+//   - error: 如果创建文件监听器或监听目录失败，返回相应的错误信息
+func (m *Manager) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建配置文件监听器时出错: %w", err)
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听配置文件目录 %s 时出错: %w", dir, err)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	filename := filepath.Base(m.path)
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(sighup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// 只关心目标配置文件自身的写入/创建/重命名事件，忽略目录下其他文件的变化
+				if filepath.Base(event.Name) != filename {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				m.reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching config file: %v", err)
+			case <-sighup:
+				log.Println("Received SIGHUP, reloading configuration...")
+				m.reload()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reload 重新加载配置文件，对比与当前快照的差异，并仅对发生变化的子配置通知相应的订阅者
+func (m *Manager) reload() {
+	newCfg, err := LoadConfig(m.path)
+	if err != nil {
+		log.Printf("Error reloading configuration, keeping previous configuration in effect: %v", err)
+		return
+	}
+	ApplyOverrides(newCfg, m.overrides)
+
+	if err := newCfg.Validate(); err != nil {
+		log.Printf("Reloaded configuration is invalid, keeping previous configuration in effect: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	oldCfg := m.cfg
+	m.cfg = newCfg
+	dbSubs := append([]DatabaseChangeFunc(nil), m.dbSubs...)
+	minioSubs := append([]MinioChangeFunc(nil), m.minioSubs...)
+	serverSubs := append([]ServerChangeFunc(nil), m.serverSubs...)
+	m.mu.Unlock()
+
+	if !reflect.DeepEqual(oldCfg.Database, newCfg.Database) {
+		for _, fn := range dbSubs {
+			fn(oldCfg.Database, newCfg.Database)
+		}
+	}
+	if !reflect.DeepEqual(oldCfg.Minio, newCfg.Minio) {
+		for _, fn := range minioSubs {
+			fn(oldCfg.Minio, newCfg.Minio)
+		}
+	}
+	if !reflect.DeepEqual(oldCfg.Server, newCfg.Server) {
+		for _, fn := range serverSubs {
+			fn(oldCfg.Server, newCfg.Server)
+		}
+	}
+
+	log.Println("Configuration reloaded successfully.")
+}
@@ -0,0 +1,106 @@
+// package config 包含应用程序配置的加载和管理功能
+package config
+
+import (
+	"fmt"     // 格式化输出
+	"strings" // 用于拼接MultiError的错误信息
+)
+
+// validSSLModes 是lib/pq支持的sslmode取值，参考database/sql驱动文档
+var validSSLModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+	"allow":       true,
+	"prefer":      true,
+}
+
+// placeholderAdminPassword 是EnsureDefaultAdmin在未显式配置Security.AdminBootstrapPassword时
+// 使用的众所周知的占位密码，Validate会在非dev Profile下拒绝它
+const placeholderAdminPassword = "admin123"
+
+// MultiError 聚合配置校验过程中发现的多个错误，Error()将它们以"; "拼接为一行，便于一次性展示给运维人员
+type MultiError struct {
+	Errors []error
+}
+
+// Error 实现error接口，将所有子错误以"; "拼接为一段文本
+func (m *MultiError) Error() string {
+	lines := make([]string, 0, len(m.Errors))
+	for _, err := range m.Errors {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "; ")
+}
+
+// add 追加一条校验错误
+func (m *MultiError) add(format string, args ...interface{}) {
+	m.Errors = append(m.Errors, fmt.Errorf(format, args...))
+}
+
+// Validate 检查配置是否满足启动应用所需的最低要求，发现的所有问题都会被收集进返回的*MultiError，
+// 而不是发现第一个问题就提前返回，方便运维人员一次性看到所有需要修复的地方
+//   - error: 配置合法时返回nil；否则返回*MultiError，其中Errors字段包含每一条具体问题
+func (c *Config) Validate() error {
+	merr := &MultiError{}
+
+	if c.Database.Host == "" {
+		merr.add("database.host 不能为空")
+	}
+	if c.Database.User == "" {
+		merr.add("database.user 不能为空")
+	}
+	if c.Database.DBName == "" {
+		merr.add("database.dbname 不能为空")
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		merr.add("database.port 不是合法的端口号: %d", c.Database.Port)
+	}
+	if c.Database.SSLMode != "" && !validSSLModes[c.Database.SSLMode] {
+		merr.add("database.sslmode 取值不合法: %q", c.Database.SSLMode)
+	}
+
+	if c.Minio.Endpoint == "" {
+		merr.add("minio.endpoint 不能为空")
+	}
+	if c.Minio.AccessKeyID == "" {
+		merr.add("minio.accessKeyID 不能为空")
+	}
+	if c.Minio.SecretAccessKey == "" {
+		merr.add("minio.secretAccessKey 不能为空")
+	}
+	if c.Minio.BucketName == "" {
+		merr.add("minio.bucketName 不能为空")
+	}
+
+	if c.Server.Port == "" {
+		merr.add("server.port 不能为空")
+	}
+
+	switch c.JWT.Algorithm {
+	case "", "HS256":
+		if c.JWT.Secret == "" {
+			merr.add("jwt.secret 在algorithm为HS256（默认）时不能为空")
+		}
+	case "RS256":
+		if c.JWT.PrivateKeyPath == "" || c.JWT.PublicKeyPath == "" {
+			merr.add("jwt.privateKeyPath 和 jwt.publicKeyPath 在algorithm为RS256时不能为空")
+		}
+	default:
+		merr.add("jwt.algorithm 取值不合法: %q，仅支持HS256/RS256", c.JWT.Algorithm)
+	}
+
+	// 非dev Profile下，默认管理员密码不能留空（回退到众所周知的占位密码）或显式配置为该占位密码，
+	// 否则生产环境会出现一个密码公开可查的admin账号
+	if c.Profile != "dev" {
+		if c.Security.AdminBootstrapPassword == "" || c.Security.AdminBootstrapPassword == placeholderAdminPassword {
+			merr.add("security.adminBootstrapPassword 在非dev环境下不能留空或使用占位密码 %q，请显式设置一个强密码", placeholderAdminPassword)
+		}
+	}
+
+	if len(merr.Errors) > 0 {
+		return merr
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+// package database 包含数据库连接和操作的相关功能
+package database
+
+import (
+	"database/sql" // 用于识别sql.ErrNoRows
+	"errors"       // 用于定义哨兵错误
+	"fmt"          // 格式化输出
+	"strings"      // 用于拼接动态检索条件
+
+	"github.com/lib/pq" // 用于操作Postgres的数组类型及识别唯一约束冲突错误
+
+	"github.com/sulibao/knowledge/internal/models" // 导入数据模型包
+)
+
+// ErrDuplicateContent 表示写入的sha256摘要与库中已有对象重复，调用方应将其视为重复内容处理
+var ErrDuplicateContent = errors.New("文件内容的sha256摘要已存在，视为重复内容")
+
+// FileStore 用于维护files表中的对象元数据索引
+// 该索引由internal/events包中的事件处理器在对象创建/删除时异步维护，而非由上传/下载接口同步写入
+type FileStore struct {
+	db DBTX // 可以是*sql.DB，也可以是*Pool以支持配置热重载时整体切换连接
+}
+
+// NewFileStore 创建并返回一个新的FileStore实例
+//   - db: 数据库连接对象，可传入*sql.DB或支持热重载的*Pool
+//   - *FileStore: 新创建的FileStore实例
+func NewFileStore(db DBTX) *FileStore {
+	return &FileStore{db: db}
+}
+
+// UpsertFile 插入或更新指定对象的元数据索引
+// 对象名称重复时覆盖原记录（对应MinIO中的同名对象覆盖），并重置sha256和content_text字段，等待后续重新计算
+// search_vector基于name和tags重新计算，正文全文检索向量需等待内容提取处理器写入content_text后再次更新
+//   - file: 待写入的文件元数据
+//   - error: 如果写入过程中发生错误，返回相应的错误信息
+func (s *FileStore) UpsertFile(file *models.File) error {
+	_, err := s.db.Exec(
+		`INSERT INTO files (name, size, etag, content_type, uploader, tags, content_text, search_vector)
+		 VALUES ($1, $2, $3, $4, $5, $6, '', to_tsvector('simple', $1 || ' ' || array_to_string($6::text[], ' ')))
+		 ON CONFLICT (name) DO UPDATE SET
+			size = EXCLUDED.size,
+			etag = EXCLUDED.etag,
+			content_type = EXCLUDED.content_type,
+			uploader = EXCLUDED.uploader,
+			tags = EXCLUDED.tags,
+			sha256 = '',
+			content_text = '',
+			search_vector = EXCLUDED.search_vector`,
+		file.Name, file.Size, file.ETag, file.ContentType, file.Uploader, pq.Array(file.Tags),
+	)
+	if err != nil {
+		return fmt.Errorf("写入文件索引时出错: %w", err)
+	}
+	return nil
+}
+
+// DeleteFileByName 从索引中移除指定名称的对象
+//   - name: 对象名称
+//   - error: 如果删除过程中发生错误，返回相应的错误信息
+func (s *FileStore) DeleteFileByName(name string) error {
+	_, err := s.db.Exec("DELETE FROM files WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("删除文件索引时出错: %w", err)
+	}
+	return nil
+}
+
+// SetSHA256 为指定对象写入其内容的sha256摘要
+// files表对非空sha256建有唯一索引，若该摘要已属于另一个对象，返回ErrDuplicateContent
+//   - name: 对象名称
+//   - sha256 string: 摘要的十六进制编码
+//   - error: 如果写入过程中发生错误，返回相应的错误信息；摘要重复时返回ErrDuplicateContent
+func (s *FileStore) SetSHA256(name, sha256 string) error {
+	_, err := s.db.Exec("UPDATE files SET sha256 = $1 WHERE name = $2", sha256, name)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDuplicateContent
+		}
+		return fmt.Errorf("写入文件sha256摘要时出错: %w", err)
+	}
+	return nil
+}
+
+// SetContentText 写入由可插拔内容提取器解析出的正文纯文本，并重新计算search_vector使正文参与全文检索
+//   - name: 对象名称
+//   - text: 提取出的正文纯文本，不支持提取的格式传入空字符串即可
+//   - error: 如果写入过程中发生错误，返回相应的错误信息
+func (s *FileStore) SetContentText(name, text string) error {
+	_, err := s.db.Exec(
+		`UPDATE files SET
+			content_text = $1,
+			search_vector = to_tsvector('simple', name || ' ' || array_to_string(tags, ' ') || ' ' || $1)
+		 WHERE name = $2`,
+		text, name,
+	)
+	if err != nil {
+		return fmt.Errorf("写入文件正文内容时出错: %w", err)
+	}
+	return nil
+}
+
+// UpdateTags 更新指定文件的标签，并重新计算search_vector使新标签立即参与全文检索
+//   - name: 对象名称
+//   - tags: 新的标签列表，整体覆盖原有标签
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *FileStore) UpdateTags(name string, tags []string) error {
+	result, err := s.db.Exec(
+		`UPDATE files SET
+			tags = $1,
+			search_vector = to_tsvector('simple', name || ' ' || array_to_string($1::text[], ' ') || ' ' || content_text)
+		 WHERE name = $2`,
+		pq.Array(tags), name,
+	)
+	if err != nil {
+		return fmt.Errorf("更新文件标签时出错: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("检查文件标签更新结果时出错: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("文件不存在: %s", name)
+	}
+	return nil
+}
+
+// SearchParams 描述一次文件检索请求的过滤、排序与分页条件
+type SearchParams struct {
+	Query       string // 全文检索关键词，匹配search_vector（文件名/标签/正文），留空表示不限
+	Uploader    string // 按上传者用户名过滤，留空表示不限
+	ContentType string // 按内容类型过滤，留空表示不限
+	Tag         string // 按标签过滤（必须包含该标签），留空表示不限
+	MinSize     int64  // 文件大小下限（字节），小于等于0表示不限
+	MaxSize     int64  // 文件大小上限（字节），小于等于0表示不限
+	Sort        string // 排序方式：relevance（相关度，需配合Query使用）/size/modified，默认按文件名排序
+	Page        int    // 页码，从1开始
+	PageSize    int    // 每页数量
+}
+
+// SearchFiles 按给定条件检索文件索引，返回分页后的结果及匹配的总数
+//   - p: 检索条件
+//   - []*models.File: 当前页的文件元数据列表
+//   - int: 满足过滤条件的总数，用于分页
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *FileStore) SearchFiles(p SearchParams) ([]*models.File, int, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if p.Query != "" {
+		conditions = append(conditions, fmt.Sprintf("search_vector @@ plainto_tsquery('simple', %s)", arg(p.Query)))
+	}
+	if p.Uploader != "" {
+		conditions = append(conditions, fmt.Sprintf("uploader = %s", arg(p.Uploader)))
+	}
+	if p.ContentType != "" {
+		conditions = append(conditions, fmt.Sprintf("content_type = %s", arg(p.ContentType)))
+	}
+	if p.Tag != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = ANY(tags)", arg(p.Tag)))
+	}
+	if p.MinSize > 0 {
+		conditions = append(conditions, fmt.Sprintf("size >= %s", arg(p.MinSize)))
+	}
+	if p.MaxSize > 0 {
+		conditions = append(conditions, fmt.Sprintf("size <= %s", arg(p.MaxSize)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy := "name ASC"
+	switch p.Sort {
+	case "size":
+		orderBy = "size DESC"
+	case "modified":
+		orderBy = "created_at DESC"
+	case "relevance":
+		if p.Query != "" {
+			orderBy = fmt.Sprintf("ts_rank(search_vector, plainto_tsquery('simple', %s)) DESC", arg(p.Query))
+		}
+	}
+
+	limitPlaceholder := arg(p.PageSize)
+	offsetPlaceholder := arg((p.Page - 1) * p.PageSize)
+
+	query := fmt.Sprintf(
+		`SELECT id, name, size, etag, content_type, uploader, sha256, tags, created_at, COUNT(*) OVER() AS total
+		 FROM files %s ORDER BY %s LIMIT %s OFFSET %s`,
+		where, orderBy, limitPlaceholder, offsetPlaceholder,
+	)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("检索文件时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	var total int
+	for rows.Next() {
+		var file models.File
+		if err := rows.Scan(
+			&file.ID, &file.Name, &file.Size, &file.ETag, &file.ContentType,
+			&file.Uploader, &file.SHA256, pq.Array(&file.Tags), &file.CreatedAt, &total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("扫描检索结果时出错: %w", err)
+		}
+		files = append(files, &file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历检索结果时出错: %w", err)
+	}
+	return files, total, nil
+}
+
+// ListFiles 返回索引中的全部文件元数据，替代直接调用MinIO的ListObjects，在大存储桶下响应更快
+//   - []*models.File: 文件元数据列表
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *FileStore) ListFiles() ([]*models.File, error) {
+	rows, err := s.db.Query(
+		"SELECT id, name, size, etag, content_type, uploader, sha256, tags, created_at FROM files ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件索引列表时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		var file models.File
+		if err := rows.Scan(
+			&file.ID, &file.Name, &file.Size, &file.ETag, &file.ContentType,
+			&file.Uploader, &file.SHA256, pq.Array(&file.Tags), &file.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("扫描文件索引列表时出错: %w", err)
+		}
+		files = append(files, &file)
+	}
+	return files, rows.Err()
+}
+
+// GetFileByName 按名称查询索引中的单个对象元数据，找不到时返回(nil, nil)
+// 主要供下载/预签名下载路径按对象的uploader（而非发起下载请求的用户）派生sse-c客户密钥使用
+//   - name: 对象名称
+//   - *models.File: 对象元数据，不存在时为nil
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *FileStore) GetFileByName(name string) (*models.File, error) {
+	var file models.File
+	err := s.db.QueryRow(
+		"SELECT id, name, size, etag, content_type, uploader, sha256, tags, created_at FROM files WHERE name = $1",
+		name,
+	).Scan(
+		&file.ID, &file.Name, &file.Size, &file.ETag, &file.ContentType,
+		&file.Uploader, &file.SHA256, pq.Array(&file.Tags), &file.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询文件索引时出错: %w", err)
+	}
+	return &file, nil
+}
+
+// ListNamesByUploader 返回指定上传者名下已索引的全部对象名称
+// 供密钥轮换等需要按对象归属用户而非全量存储桶重新加密的场景使用
+//   - uploader: 上传者用户名
+//   - []string: 该用户名下的对象名称列表
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *FileStore) ListNamesByUploader(uploader string) ([]string, error) {
+	rows, err := s.db.Query("SELECT name FROM files WHERE uploader = $1", uploader)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户名下对象名称列表时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("扫描用户名下对象名称列表时出错: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// ListAllNames 返回索引中全部对象的名称，供协调扫描时与MinIO的实际对象列表比对
+//   - []string: 对象名称列表
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *FileStore) ListAllNames() ([]string, error) {
+	rows, err := s.db.Query("SELECT name FROM files")
+	if err != nil {
+		return nil, fmt.Errorf("查询文件索引名称列表时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("扫描文件索引名称列表时出错: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
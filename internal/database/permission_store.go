@@ -0,0 +1,56 @@
+// package database 包含数据库连接和操作的相关功能
+package database
+
+import (
+	"database/sql" // 提供SQL数据库接口
+	"fmt"          // 格式化输出
+)
+
+// PermissionStore 用于单独读写用户的权限位掩码
+// 权限位掩码直接存储在users表的permission列中（见models.DefaultPermission），
+// 之所以拆出独立的Store而不是并入UserStore，是为了让"调整能力位"这一操作
+// 在调用方视角上与"调整角色路径策略"（UpdateUserRole）区分开来
+type PermissionStore struct {
+	db DBTX // 可以是*sql.DB，也可以是*Pool以支持配置热重载时整体切换连接
+}
+
+// NewPermissionStore 创建并返回一个新的PermissionStore实例
+//   - db: 数据库连接对象，可传入*sql.DB或支持热重载的*Pool
+//   - *PermissionStore: 新创建的PermissionStore实例
+func NewPermissionStore(db DBTX) *PermissionStore {
+	return &PermissionStore{db: db}
+}
+
+// GetPermission 返回指定用户当前的权限位掩码
+//   - userID: 用户ID
+//   - int: 权限位掩码
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *PermissionStore) GetPermission(userID int) (int, error) {
+	var permission int
+	err := s.db.QueryRow("SELECT permission FROM users WHERE id = $1", userID).Scan(&permission)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("用户不存在: %d", userID)
+	} else if err != nil {
+		return 0, fmt.Errorf("查询用户权限位掩码时出错: %w", err)
+	}
+	return permission, nil
+}
+
+// UpdatePermission 更新指定用户的权限位掩码，用于在角色默认权限之外单独调整
+//   - userID: 用户ID
+//   - permission: 新的权限位掩码
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *PermissionStore) UpdatePermission(userID int, permission int) error {
+	result, err := s.db.Exec("UPDATE users SET permission = $1, updated_at = now() WHERE id = $2", permission, userID)
+	if err != nil {
+		return fmt.Errorf("更新用户权限位掩码时出错: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("检查用户权限位掩码更新结果时出错: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("用户不存在: %d", userID)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// package database 包含数据库连接和操作的相关功能
+package database
+
+import (
+	"database/sql" // SQL数据库接口
+	"log"          // 日志记录
+	"sync"         // 用于保护连接指针的并发读写
+
+	"github.com/sulibao/knowledge/internal/config"
+)
+
+// DBTX 抽象出各Store实际用到的*sql.DB方法，使Pool可以在不改变Store内部调用方式的前提下
+// 让底层连接在运行时被整体替换（配置热重载时）。*sql.DB本身满足该接口
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Pool 持有一个可被整体替换的*sql.DB连接，对外仍然只暴露DBTX这三个方法
+// 各Store构造时传入*Pool即可在config.Manager触发OnDatabaseChange时透明地切换到新连接，
+// 而无需感知连接本身在何时被替换
+type Pool struct {
+	mu sync.RWMutex
+	db *sql.DB
+}
+
+// NewPool 基于已建立的数据库连接创建一个Pool
+//   - db: 已经建立好的数据库连接
+//   - *Pool: 新创建的Pool实例
+func NewPool(db *sql.DB) *Pool {
+	return &Pool{db: db}
+}
+
+// Exec 委托给当前持有的连接执行
+func (p *Pool) Exec(query string, args ...interface{}) (sql.Result, error) {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	return db.Exec(query, args...)
+}
+
+// Query 委托给当前持有的连接执行
+func (p *Pool) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	return db.Query(query, args...)
+}
+
+// QueryRow 委托给当前持有的连接执行
+func (p *Pool) QueryRow(query string, args ...interface{}) *sql.Row {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	return db.QueryRow(query, args...)
+}
+
+// Reload 以新的数据库配置建立连接并整体替换当前连接，符合config.DatabaseChangeFunc的签名，
+// 可直接传给config.Manager.OnDatabaseChange注册。旧连接在新连接建立成功后才关闭，
+// 避免替换过程中出现短暂的"无可用连接"窗口；新连接建立失败时保留旧连接不变
+//   - old: 变更前的数据库配置（未使用，仅用于匹配订阅者签名）
+//   - newCfg: 变更后的数据库配置
+func (p *Pool) Reload(old, newCfg config.DatabaseConfig) {
+	newDB, err := InitPostgres(&config.Config{Database: newCfg})
+	if err != nil {
+		log.Printf("Error establishing database connection with reloaded configuration, keeping previous connection in effect: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	oldDB := p.db
+	p.db = newDB
+	p.mu.Unlock()
+
+	if err := oldDB.Close(); err != nil {
+		log.Printf("Error closing previous database connection after reload: %v", err)
+	}
+	log.Println("Database connection reloaded successfully.")
+}
+
+var _ DBTX = (*Pool)(nil)
+var _ DBTX = (*sql.DB)(nil)
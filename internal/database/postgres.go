@@ -7,6 +7,7 @@ import (
 	"log"          // 日志记录
 
 	"github.com/sulibao/knowledge/internal/config"
+	"github.com/sulibao/knowledge/internal/models"
 
 	_ "github.com/lib/pq" // PostgreSQL驱动程序，使用下划线导入表示仅初始化驱动
 )
@@ -73,14 +74,23 @@ func InitPostgres(cfg *config.Config) (*sql.DB, error) {
 
 // CreateTables 在数据库中创建必要的表结构（如果不存在）
 //   - 创建用户表，用于存储用户信息
+//   - 创建刷新令牌表，用于JWT刷新令牌的持久化与吊销
+//   - 创建casbin_rule表，并播种默认的角色策略
 //   - db: 数据库连接对象
 //   - error: 如果创建表过程中发生错误，返回相应的错误信息
 func CreateTables(db *sql.DB) error {
 	createUsersTableSQL := `
 	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,                    -- 用户ID，自增主键
-		username VARCHAR(50) UNIQUE NOT NULL,     -- 用户名，唯一且非空
-		password VARCHAR(255) NOT NULL            -- 密码哈希，非空
+		id SERIAL PRIMARY KEY,                         -- 用户ID，自增主键
+		username VARCHAR(50) UNIQUE NOT NULL,          -- 用户名，唯一且非空
+		password VARCHAR(255) NOT NULL,                -- 密码哈希，非空
+		role VARCHAR(20) NOT NULL DEFAULT 'viewer',    -- 角色，用于Casbin鉴权
+		encryption_key_id VARCHAR(64) NOT NULL DEFAULT '', -- 加密密钥版本号，用于派生SSE-C密钥
+		email VARCHAR(255) NOT NULL DEFAULT '',        -- 邮箱地址，允许为空
+		permission INTEGER NOT NULL DEFAULT 0,         -- 权限位掩码，Role对应路径策略之外的细粒度能力位
+		is_active BOOLEAN NOT NULL DEFAULT TRUE,       -- 账号是否启用
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- 账号创建时间
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()  -- 账号信息最近一次更新时间
 	);
 	`
 
@@ -90,6 +100,194 @@ func CreateTables(db *sql.DB) error {
 		return fmt.Errorf("创建用户表时发生错误: %w", err)
 	}
 
+	// 兼容已存在的users表（旧版本没有role列），补充role列
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer'`)
+	if err != nil {
+		return fmt.Errorf("为用户表添加role列时发生错误: %w", err)
+	}
+
+	// 补充encryption_key_id列，用于派生该用户的SSE-C密钥，支持后续的密钥轮换
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS encryption_key_id VARCHAR(64) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("为用户表添加encryption_key_id列时发生错误: %w", err)
+	}
+
+	// 补充用户生命周期管理所需的列：邮箱、权限位掩码、启用状态、创建/更新时间
+	for _, stmt := range []string{
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS permission INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now()`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now()`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("为用户表添加用户生命周期相关列时发生错误: %w", err)
+		}
+	}
+
+	// 回填已存在的admin账号：role/permission列刚引入时的默认值分别是'viewer'/0，
+	// 若不回填，已在运行的旧部署升级后会把预置的admin账号连带降级为无权限的viewer，
+	// 导致Casbin的admin角色策略和ManageUsers权限位同时失效，无人能再访问管理接口
+	_, err = db.Exec(
+		`UPDATE users SET role = $1, permission = $2 WHERE username = 'admin' AND (role <> $1 OR permission <> $2)`,
+		models.RoleAdmin, models.DefaultPermission(models.RoleAdmin),
+	)
+	if err != nil {
+		return fmt.Errorf("回填默认管理员角色和权限时发生错误: %w", err)
+	}
+
 	log.Println("Users table checked/created successfully.")
+
+	createRefreshTokensTableSQL := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id SERIAL PRIMARY KEY,                      -- 刷新令牌ID，自增主键
+		username VARCHAR(50) NOT NULL,              -- 所属用户名
+		jti VARCHAR(64) UNIQUE NOT NULL,            -- 令牌唯一标识，用于吊销检索
+		token_hash VARCHAR(255) NOT NULL,           -- 刷新令牌的哈希值（不存明文）
+		expires_at TIMESTAMPTZ NOT NULL,            -- 过期时间
+		revoked BOOLEAN NOT NULL DEFAULT FALSE,     -- 是否已被吊销
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now() -- 签发时间
+	);
+	`
+	_, err = db.Exec(createRefreshTokensTableSQL)
+	if err != nil {
+		return fmt.Errorf("创建刷新令牌表时发生错误: %w", err)
+	}
+
+	// 补充会话审计所需的列：签发时的客户端UA/IP，以及被吊销的具体时间点
+	// （revoked布尔列继续保留作为吊销状态的权威来源，revoked_at仅用于审计展示）
+	for _, stmt := range []string{
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("为刷新令牌表添加会话审计相关列时发生错误: %w", err)
+		}
+	}
+	log.Println("Refresh tokens table checked/created successfully.")
+
+	// casbin_rule使用casbin官方惯用的通用策略表结构（ptype, v0..v5）
+	createCasbinRuleTableSQL := `
+	CREATE TABLE IF NOT EXISTS casbin_rule (
+		id SERIAL PRIMARY KEY,
+		ptype VARCHAR(10) NOT NULL DEFAULT '',
+		v0 VARCHAR(100) NOT NULL DEFAULT '',
+		v1 VARCHAR(100) NOT NULL DEFAULT '',
+		v2 VARCHAR(100) NOT NULL DEFAULT '',
+		v3 VARCHAR(100) NOT NULL DEFAULT '',
+		v4 VARCHAR(100) NOT NULL DEFAULT '',
+		v5 VARCHAR(100) NOT NULL DEFAULT ''
+	);
+	`
+	_, err = db.Exec(createCasbinRuleTableSQL)
+	if err != nil {
+		return fmt.Errorf("创建casbin_rule表时发生错误: %w", err)
+	}
+	log.Println("Casbin rule table checked/created successfully.")
+
+	if err := seedDefaultCasbinRules(db); err != nil {
+		return fmt.Errorf("播种默认casbin策略时发生错误: %w", err)
+	}
+
+	// uploads表用于跟踪进行中的分片上传会话，使客户端能够在断连后恢复上传
+	createUploadsTableSQL := `
+	CREATE TABLE IF NOT EXISTS uploads (
+		id TEXT PRIMARY KEY,                          -- 上传会话ID，作为uploadId返回给客户端
+		object_key TEXT NOT NULL,                     -- 最终存储在MinIO中的对象名称
+		user_id INTEGER NOT NULL REFERENCES users(id),-- 发起上传的用户
+		minio_upload_id TEXT NOT NULL,                -- MinIO（S3协议）分片上传ID
+		parts JSONB NOT NULL DEFAULT '[]',            -- 已上传分片列表，[{partNumber, etag, size}, ...]
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now() -- 会话创建时间
+	);
+	`
+	if _, err := db.Exec(createUploadsTableSQL); err != nil {
+		return fmt.Errorf("创建分片上传会话表时发生错误: %w", err)
+	}
+	log.Println("Uploads table checked/created successfully.")
+
+	// files表是MinIO存储桶的元数据索引，由internal/events包中的桶事件处理器异步维护
+	// ListFiles等读路径依赖该索引而非直接调用ListObjects，在大存储桶下响应更快
+	createFilesTableSQL := `
+	CREATE TABLE IF NOT EXISTS files (
+		id SERIAL PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,                    -- 对象名称，即MinIO中的对象键
+		size BIGINT NOT NULL DEFAULT 0,               -- 文件大小（字节）
+		etag TEXT NOT NULL DEFAULT '',                -- MinIO返回的ETag
+		content_type TEXT NOT NULL DEFAULT '',        -- 内容类型
+		uploader TEXT NOT NULL DEFAULT '',            -- 上传者用户名，来自对象的用户自定义元数据
+		sha256 TEXT NOT NULL DEFAULT '',              -- 文件内容的sha256摘要，用于去重
+		tags TEXT[] NOT NULL DEFAULT '{}',            -- 用户自定义标签，参与全文检索
+		content_text TEXT NOT NULL DEFAULT '',        -- 由可插拔提取器解析出的正文纯文本，参与全文检索
+		search_vector TSVECTOR,                       -- 基于name/tags/content_text计算出的全文检索向量
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now() -- 索引写入时间
+	);
+	`
+	if _, err := db.Exec(createFilesTableSQL); err != nil {
+		return fmt.Errorf("创建文件索引表时发生错误: %w", err)
+	}
+	log.Println("Files table checked/created successfully.")
+
+	// sha256为空字符串时对象尚未完成摘要计算，不参与去重；仅对非空摘要强制唯一，用于检测重复内容
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_files_sha256_unique ON files (sha256) WHERE sha256 != ''`)
+	if err != nil {
+		return fmt.Errorf("创建文件sha256唯一索引时发生错误: %w", err)
+	}
+	log.Println("Files sha256 unique index checked/created successfully.")
+
+	// search_vector使用GIN索引加速全文检索，FileStore在写入name/tags/content_text的同时一并维护该列
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_files_search_vector ON files USING GIN (search_vector)`)
+	if err != nil {
+		return fmt.Errorf("创建文件全文检索索引时发生错误: %w", err)
+	}
+	log.Println("Files search_vector GIN index checked/created successfully.")
+
+	return nil
+}
+
+// seedDefaultCasbinRules 在casbin_rule表为空时写入默认的角色策略
+//   - admin可以访问全部/api/*资源
+//   - uploader可以上传、下载、列出和删除文件，但不能管理用户
+//   - viewer只能列出和下载文件
+//   - db: 数据库连接对象
+//   - error: 如果写入过程中发生错误，返回相应的错误信息
+func seedDefaultCasbinRules(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM casbin_rule").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		// 已经有策略数据，不再重复播种
+		return nil
+	}
+
+	defaultPolicies := [][]string{
+		{"p", "admin", "/api/*", "*"}, // 管理员可访问全部/api/*资源，含新增的/api/keys/rotate
+		{"p", "uploader", "/api/upload", "POST"},
+		{"p", "uploader", "/api/uploads*", "*"},
+		{"p", "uploader", "/api/presign/upload", "POST"},
+		{"p", "uploader", "/api/presign/download", "GET"},
+		{"p", "uploader", "/api/files", "GET"},
+		{"p", "uploader", "/api/download", "GET"},
+		{"p", "uploader", "/api/delete", "DELETE"},
+		{"p", "viewer", "/api/files", "GET"},
+		{"p", "viewer", "/api/download", "GET"},
+		{"p", "viewer", "/api/presign/download", "GET"},
+		{"p", "uploader", "/api/search", "GET"},
+		{"p", "uploader", "/api/files/*", "PATCH"},
+		{"p", "viewer", "/api/search", "GET"},
+	}
+
+	for _, policy := range defaultPolicies {
+		_, err := db.Exec(
+			"INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES ($1, $2, $3, $4)",
+			policy[0], policy[1], policy[2], policy[3],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Println("Default casbin policies seeded successfully.")
 	return nil
 }
@@ -0,0 +1,130 @@
+// package database 包含数据库连接和操作的相关功能
+package database
+
+import (
+	"database/sql"  // 提供SQL数据库接口
+	"encoding/json" // 用于parts字段的JSONB编解码
+	"fmt"           // 格式化输出
+
+	"github.com/sulibao/knowledge/internal/models" // 导入数据模型包
+)
+
+// UploadStore 提供对分片上传会话（uploads表）的持久化操作
+type UploadStore struct {
+	db DBTX // 可以是*sql.DB，也可以是*Pool以支持配置热重载时整体切换连接
+}
+
+// NewUploadStore 创建并返回一个新的UploadStore实例
+//   - db: 数据库连接对象，可传入*sql.DB或支持热重载的*Pool
+//   - *UploadStore: 新创建的UploadStore实例
+func NewUploadStore(db DBTX) *UploadStore {
+	return &UploadStore{db: db}
+}
+
+// CreateSession 创建一条新的分片上传会话记录
+//   - userID: 发起上传的用户ID
+//   - objectKey: 最终存储在MinIO中的对象名称
+//   - minioUploadID: MinIO（S3协议）返回的分片上传ID
+//   - *models.UploadSession: 创建成功的会话
+//   - error: 如果生成ID或写入过程中发生错误，返回相应的错误信息
+func (s *UploadStore) CreateSession(userID int, objectKey, minioUploadID string) (*models.UploadSession, error) {
+	id, err := generateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("生成上传会话ID时出错: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO uploads (id, object_key, user_id, minio_upload_id, parts) VALUES ($1, $2, $3, $4, '[]')",
+		id, objectKey, userID, minioUploadID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建分片上传会话时出错: %w", err)
+	}
+
+	return s.GetSession(id)
+}
+
+// GetSession 根据上传会话ID查询会话信息
+//   - id: 上传会话ID
+//   - *models.UploadSession: 如果会话存在，返回会话信息；如果不存在，返回nil
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UploadStore) GetSession(id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	var partsJSON []byte
+
+	err := s.db.QueryRow(
+		"SELECT id, object_key, user_id, minio_upload_id, parts, created_at FROM uploads WHERE id = $1", id,
+	).Scan(&session.ID, &session.ObjectKey, &session.UserID, &session.MinioUploadID, &partsJSON, &session.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil // 会话不存在
+	} else if err != nil {
+		return nil, fmt.Errorf("查询分片上传会话时出错: %w", err)
+	}
+
+	if err := json.Unmarshal(partsJSON, &session.Parts); err != nil {
+		return nil, fmt.Errorf("解析已上传分片列表时出错: %w", err)
+	}
+	return &session, nil
+}
+
+// AddPart 将一个新上传完成的分片追加到会话的parts列表中
+// 如果该分片编号此前已存在（例如客户端重试），则覆盖旧记录
+//   - id: 上传会话ID
+//   - part: 刚上传完成的分片信息
+//   - error: 如果会话不存在或写入过程中发生错误，返回相应的错误信息
+func (s *UploadStore) AddPart(id string, part models.UploadPart) error {
+	session, err := s.GetSession(id)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return fmt.Errorf("上传会话不存在: %s", id)
+	}
+
+	replaced := false
+	for i, p := range session.Parts {
+		if p.PartNumber == part.PartNumber {
+			session.Parts[i] = part
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		session.Parts = append(session.Parts, part)
+	}
+
+	partsJSON, err := json.Marshal(session.Parts)
+	if err != nil {
+		return fmt.Errorf("序列化已上传分片列表时出错: %w", err)
+	}
+
+	_, err = s.db.Exec("UPDATE uploads SET parts = $1 WHERE id = $2", partsJSON, id)
+	if err != nil {
+		return fmt.Errorf("更新已上传分片列表时出错: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession 删除一条分片上传会话记录（上传完成或被中止后清理）
+//   - id: 上传会话ID
+//   - error: 如果删除过程中发生错误，返回相应的错误信息
+func (s *UploadStore) DeleteSession(id string) error {
+	_, err := s.db.Exec("DELETE FROM uploads WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("删除分片上传会话时出错: %w", err)
+	}
+	return nil
+}
+
+// CountActiveSessionsForUser 统计指定用户当前进行中的分片上传数量，用于并发数限制
+//   - userID: 用户ID
+//   - int: 进行中的上传会话数量
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UploadStore) CountActiveSessionsForUser(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM uploads WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("统计用户进行中上传数量时出错: %w", err)
+	}
+	return count, nil
+}
@@ -2,41 +2,45 @@
 package database
 
 import (
-	"database/sql" // 提供SQL数据库接口
-	"fmt"          // 格式化输出
-	"log"          // 日志记录
+	"crypto/rand"   // 用于生成随机令牌
+	"crypto/sha256" // 用于刷新令牌的哈希存储
+	"database/sql"  // 提供SQL数据库接口
+	"encoding/hex"  // 用于令牌和哈希值的十六进制编码
+	"fmt"           // 格式化输出
+	"log"           // 日志记录
+	"time"          // 时间相关操作
 
-	"github.com/sulibao/knowledge/internal/models" // 导入数据模型包
-
-	"golang.org/x/crypto/bcrypt" // 用于密码哈希和验证
+	"github.com/sulibao/knowledge/internal/models"   // 导入数据模型包
+	"github.com/sulibao/knowledge/internal/password" // 可插拔的密码哈希算法
 )
 
 type UserStore struct {
-	db *sql.DB
+	db     DBTX            // 可以是*sql.DB，也可以是*Pool以支持配置热重载时整体切换连接
+	hasher password.Hasher // 哈希新密码时使用的算法，校验时改按哈希自身的编码前缀选择算法
 }
 
 // NewUserStore 创建并返回一个新的UserStore实例
-//   - db: 数据库连接对象
+//   - db: 数据库连接对象，可传入*sql.DB或支持热重载的*Pool
+//   - hasher: 哈希新密码时使用的算法实现，通常来自config.Config.PasswordHasher()
 //   - *UserStore: 新创建的UserStore实例
-func NewUserStore(db *sql.DB) *UserStore {
-	return &UserStore{db: db}
+func NewUserStore(db DBTX, hasher password.Hasher) *UserStore {
+	return &UserStore{db: db, hasher: hasher}
 }
 
 // UpdateUserPassword 更新指定用户的密码
-//   - 对新密码进行bcrypt哈希处理
+//   - 使用当前配置的哈希算法对新密码进行哈希处理
 //   - 更新数据库中用户的密码字段
 //   - username: 要更新密码的用户名
 //   - newPassword: 新的明文密码
 //   - error: 如果更新过程中发生错误，返回相应的错误信息
 func (s *UserStore) UpdateUserPassword(username, newPassword string) error {
-	// 对新密码进行bcrypt哈希处理
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		return fmt.Errorf("在对新密码进行哈希处理时出错: %w", err)
 	}
 
 	// 执行SQL更新语句
-	_, err = s.db.Exec("UPDATE users SET password = $1 WHERE username = $2", string(hashedPassword), username)
+	_, err = s.db.Exec("UPDATE users SET password = $1 WHERE username = $2", hashedPassword, username)
 	if err != nil {
 		return fmt.Errorf("更新用户密码时出错: %w", err)
 	}
@@ -44,19 +48,37 @@ func (s *UserStore) UpdateUserPassword(username, newPassword string) error {
 }
 
 // CreateUser 在数据库中创建新用户
-//   - 对用户密码进行bcrypt哈希处理
+//   - 使用当前配置的哈希算法对用户密码进行哈希处理
 //   - 将用户信息插入数据库
-//   - user: 包含用户信息的User对象
+//   - user: 包含用户信息的User对象，Role为空时默认赋值为viewer
 //   - error: 如果创建过程中发生错误，返回相应的错误信息
 func (s *UserStore) CreateUser(user *models.User) error {
-	// 对用户密码进行bcrypt哈希处理
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(user.Password)
 	if err != nil {
 		return fmt.Errorf("在对密码进行哈希处理时出错: %w", err)
 	}
 
+	role := user.Role
+	if role == "" {
+		role = models.RoleViewer
+	}
+
+	permission := user.Permission
+	if permission == 0 {
+		permission = models.DefaultPermission(role)
+	}
+
+	// 为用户生成初始的加密密钥版本号，供sse-c模式下派生其专属客户密钥
+	encryptionKeyID, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("生成用户加密密钥版本号时出错: %w", err)
+	}
+
 	// 执行SQL插入语句
-	_, err = s.db.Exec("INSERT INTO users (username, password) VALUES ($1, $2)", user.Username, string(hashedPassword))
+	_, err = s.db.Exec(
+		"INSERT INTO users (username, password, role, encryption_key_id, email, permission, is_active) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		user.Username, hashedPassword, role, encryptionKeyID, user.Email, permission, true,
+	)
 	if err != nil {
 		return fmt.Errorf("创建用户时出错: %w", err)
 	}
@@ -71,7 +93,13 @@ func (s *UserStore) CreateUser(user *models.User) error {
 func (s *UserStore) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
 	// 执行SQL查询语句
-	err := s.db.QueryRow("SELECT id, username, password FROM users WHERE username = $1", username).Scan(&user.ID, &user.Username, &user.Password)
+	err := s.db.QueryRow(
+		`SELECT id, username, password, role, encryption_key_id, email, permission, is_active, created_at, updated_at
+		 FROM users WHERE username = $1`, username,
+	).Scan(
+		&user.ID, &user.Username, &user.Password, &user.Role, &user.EncryptionKeyID,
+		&user.Email, &user.Permission, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+	)
 	if err == sql.ErrNoRows {
 		return nil, nil // 用户不存在
 	} else if err != nil {
@@ -80,11 +108,66 @@ func (s *UserStore) GetUserByUsername(username string) (*models.User, error) {
 	return &user, nil // 返回找到的用户信息
 }
 
-// EnsureDefaultAdmin 确保系统中存在默认的管理员用户
+// FindUserByID 根据用户ID查询用户信息
+//   - id: 要查询的用户ID
+//   - *models.User: 如果用户存在，返回用户信息；如果用户不存在，返回nil
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UserStore) FindUserByID(id int) (*models.User, error) {
+	var user models.User
+	err := s.db.QueryRow(
+		`SELECT id, username, password, role, encryption_key_id, email, permission, is_active, created_at, updated_at
+		 FROM users WHERE id = $1`, id,
+	).Scan(
+		&user.ID, &user.Username, &user.Password, &user.Role, &user.EncryptionKeyID,
+		&user.Email, &user.Permission, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // 用户不存在
+	} else if err != nil {
+		return nil, fmt.Errorf("在根据ID查询用户时出错: %w", err)
+	}
+	return &user, nil
+}
+
+// GenerateEncryptionKeyID 生成一个新的加密密钥版本号，但不写入数据库
+// 密钥轮换接口应先用该版本号重新加密用户名下的全部对象，全部成功后再调用SetEncryptionKeyID
+// 持久化，避免新版本号先于重加密完成就生效，导致尚未重加密的对象无法用当前存储的版本号派生出
+// 正确的密钥解密
+//   - string: 新生成的加密密钥版本号
+//   - error: 如果生成过程中发生错误，返回相应的错误信息
+func GenerateEncryptionKeyID() (string, error) {
+	newKeyID, err := generateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("生成新加密密钥版本号时出错: %w", err)
+	}
+	return newKeyID, nil
+}
+
+// SetEncryptionKeyID 将指定用户的加密密钥版本号更新为给定值
+//   - username: 要更新的用户名
+//   - keyID: 新的加密密钥版本号
+//   - error: 如果写入过程中发生错误，返回相应的错误信息
+func (s *UserStore) SetEncryptionKeyID(username, keyID string) error {
+	_, err := s.db.Exec("UPDATE users SET encryption_key_id = $1 WHERE username = $2", keyID, username)
+	if err != nil {
+		return fmt.Errorf("更新用户加密密钥版本号时出错: %w", err)
+	}
+	return nil
+}
+
+// EnsureDefaultAdmin 确保系统中存在默认的管理员用户，且其角色和权限未被意外降级
 //   - 检查是否存在用户名为"admin"的用户
-//   - 如果不存在，创建默认管理员用户
-//   - 如果已存在，确保密码为默认值
-func (s *UserStore) EnsureDefaultAdmin() {
+//   - 如果不存在，创建默认管理员用户，角色和权限均为admin
+//   - 如果已存在但role/permission不是admin应有的值（例如角色/权限列是后补充的，
+//     旧部署升级时被迁移默认值'viewer'/0覆盖），修复为admin应有的值；不触碰密码——
+//     避免每次启动都将运维人员已修改的密码重置回默认值
+//   - initialPassword: 创建默认admin时使用的初始密码，通常来自config.Config.Security.AdminBootstrapPassword；
+//     留空时回退到占位密码"admin123"（仅建议在开发环境下使用，config.Config.Validate会在非dev Profile下拒绝该取值）
+func (s *UserStore) EnsureDefaultAdmin(initialPassword string) {
+	if initialPassword == "" {
+		initialPassword = "admin123"
+	}
+
 	// 查询是否存在admin用户
 	adminUser, err := s.GetUserByUsername("admin")
 	if err != nil {
@@ -95,21 +178,315 @@ func (s *UserStore) EnsureDefaultAdmin() {
 		// admin用户不存在，创建默认管理员
 		log.Println("Default admin user not found, creating...")
 		defaultAdmin := &models.User{
-			Username: "admin",
-			Password: "admin123", // 这个密码将在CreateUser函数中被哈希处理
+			Username:   "admin",
+			Password:   initialPassword, // 这个密码将在CreateUser函数中被哈希处理，仅作为初始值，应尽快登录后修改
+			Role:       models.RoleAdmin,
+			Permission: models.DefaultPermission(models.RoleAdmin),
 		}
 		err = s.CreateUser(defaultAdmin)
 		if err != nil {
 			log.Fatalf("Error creating default admin user: %v", err)
 		}
 		log.Println("Default admin user 'admin' created successfully.")
-	} else {
-		// admin用户已存在，确保密码为默认值
-		log.Println("Default admin user 'admin' already exists. Ensuring password is 'admin123'...")
-		err = s.UpdateUserPassword("admin", "admin123")
-		if err != nil {
-			log.Fatalf("Error updating default admin password: %v", err)
+		return
+	}
+
+	// admin用户已存在，保留其当前密码，仅在启动日志中提示
+	log.Println("Default admin user 'admin' already exists, leaving existing password untouched.")
+
+	expectedPermission := models.DefaultPermission(models.RoleAdmin)
+	if adminUser.Role != models.RoleAdmin || adminUser.Permission != expectedPermission {
+		log.Println("Default admin user 'admin' has an unexpected role/permission, repairing...")
+		if _, err := s.db.Exec(
+			"UPDATE users SET role = $1, permission = $2 WHERE username = 'admin'",
+			models.RoleAdmin, expectedPermission,
+		); err != nil {
+			log.Fatalf("Error repairing default admin role/permission: %v", err)
 		}
-		log.Println("Default admin user 'admin' password ensured to be 'admin123'.")
 	}
 }
+
+// IssueRefreshToken 为指定用户签发一条新的刷新令牌记录
+//   - 生成随机的不透明令牌，并将其哈希值和jti持久化到refresh_tokens表
+//   - username: 令牌所属的用户名
+//   - ttl: 刷新令牌的有效期
+//   - userAgent: 签发时客户端的User-Agent，留空表示未记录，仅用于会话审计
+//   - ip: 签发时客户端的IP地址，留空表示未记录，仅用于会话审计
+//   - string: 返回给客户端的明文刷新令牌
+//   - string: 本次令牌的jti，用于后续吊销
+//   - error: 如果生成或写入过程中发生错误，返回相应的错误信息
+func (s *UserStore) IssueRefreshToken(username string, ttl time.Duration, userAgent, ip string) (token string, jti string, err error) {
+	token, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("生成刷新令牌时出错: %w", err)
+	}
+	jti, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("生成jti时出错: %w", err)
+	}
+
+	tokenHash := sha256.Sum256([]byte(token))
+	expiresAt := time.Now().Add(ttl)
+
+	_, err = s.db.Exec(
+		"INSERT INTO refresh_tokens (username, jti, token_hash, expires_at, user_agent, ip) VALUES ($1, $2, $3, $4, $5, $6)",
+		username, jti, hex.EncodeToString(tokenHash[:]), expiresAt, userAgent, ip,
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("保存刷新令牌时出错: %w", err)
+	}
+	return token, jti, nil
+}
+
+// IssueRefresh 是IssueRefreshToken按用户ID（而非用户名）寻址的等价封装
+// refresh_tokens表仍以username为关联键（见IssueRefreshToken的既有设计），这里仅补充一个
+// 按ID寻址用户的入口，供更习惯于持有用户ID的调用方（如internal/auth）使用
+//   - userID: 令牌所属的用户ID
+//   - ttl: 刷新令牌的有效期
+//   - userAgent、ip: 同IssueRefreshToken
+//   - string: 返回给客户端的明文刷新令牌
+//   - string: 本次令牌的jti，用于后续吊销
+//   - error: 用户不存在或生成/写入过程中发生错误时返回相应的错误信息
+func (s *UserStore) IssueRefresh(userID int, ttl time.Duration, userAgent, ip string) (token string, jti string, err error) {
+	user, err := s.FindUserByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user == nil {
+		return "", "", fmt.Errorf("用户不存在: %d", userID)
+	}
+	return s.IssueRefreshToken(user.Username, ttl, userAgent, ip)
+}
+
+// ValidateRefreshToken 校验刷新令牌是否有效（存在、未过期、未被吊销）
+//   - token: 客户端提交的明文刷新令牌
+//   - string: 令牌所属的用户名
+//   - string: 令牌的jti
+//   - error: 令牌无效或已过期/吊销时返回错误
+func (s *UserStore) ValidateRefreshToken(token string) (username string, jti string, err error) {
+	tokenHash := sha256.Sum256([]byte(token))
+
+	var revoked bool
+	var expiresAt time.Time
+	err = s.db.QueryRow(
+		"SELECT username, jti, revoked, expires_at FROM refresh_tokens WHERE token_hash = $1",
+		hex.EncodeToString(tokenHash[:]),
+	).Scan(&username, &jti, &revoked, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("刷新令牌不存在")
+	} else if err != nil {
+		return "", "", fmt.Errorf("查询刷新令牌时出错: %w", err)
+	}
+
+	if revoked {
+		return "", "", fmt.Errorf("刷新令牌已被吊销")
+	}
+	if time.Now().After(expiresAt) {
+		return "", "", fmt.Errorf("刷新令牌已过期")
+	}
+	return username, jti, nil
+}
+
+// RevokeRefreshToken 根据jti吊销一条刷新令牌
+//   - jti: 要吊销的令牌标识
+//   - error: 如果吊销过程中发生错误，返回相应的错误信息
+func (s *UserStore) RevokeRefreshToken(jti string) error {
+	_, err := s.db.Exec("UPDATE refresh_tokens SET revoked = TRUE, revoked_at = now() WHERE jti = $1", jti)
+	if err != nil {
+		return fmt.Errorf("吊销刷新令牌时出错: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser 吊销指定用户当前全部未吊销的刷新令牌，用于"退出其他所有设备"一类的场景，
+// 或管理员怀疑某账号的会话已经泄露时强制使其全部失效
+//   - userID: 用户ID
+//   - error: 用户不存在或吊销过程中发生错误时返回相应的错误信息
+func (s *UserStore) RevokeAllForUser(userID int) error {
+	user, err := s.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("用户不存在: %d", userID)
+	}
+	_, err = s.db.Exec(
+		"UPDATE refresh_tokens SET revoked = TRUE, revoked_at = now() WHERE username = $1 AND revoked = FALSE",
+		user.Username,
+	)
+	if err != nil {
+		return fmt.Errorf("吊销用户全部刷新令牌时出错: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked 检查指定jti是否已被吊销（用于访问令牌黑名单场景下的刷新令牌关联校验）
+//   - jti: 要检查的令牌标识
+//   - bool: 是否已吊销
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UserStore) IsRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := s.db.QueryRow("SELECT revoked FROM refresh_tokens WHERE jti = $1", jti).Scan(&revoked)
+	if err == sql.ErrNoRows {
+		// 找不到记录视为已吊销，拒绝访问更安全
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("检查令牌吊销状态时出错: %w", err)
+	}
+	return revoked, nil
+}
+
+// ListUsers 返回系统中的全部用户（不含密码哈希以外的敏感信息已经由调用方自行处理）
+//   - []*models.User: 用户列表
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UserStore) ListUsers() ([]*models.User, error) {
+	rows, err := s.db.Query(
+		`SELECT id, username, password, role, encryption_key_id, email, permission, is_active, created_at, updated_at
+		 FROM users ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户列表时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID, &user.Username, &user.Password, &user.Role, &user.EncryptionKeyID,
+			&user.Email, &user.Permission, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("扫描用户列表时出错: %w", err)
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// FindAll 分页查询用户列表，支持按用户名模糊匹配
+//   - offset: 跳过的记录数
+//   - limit: 返回的最大记录数
+//   - match: 用户名模糊匹配关键词，留空表示不过滤
+//   - []*models.User: 当前页的用户列表
+//   - int: 满足匹配条件的用户总数，用于分页
+//   - error: 如果查询过程中发生错误，返回相应的错误信息
+func (s *UserStore) FindAll(offset, limit int, match string) ([]*models.User, int, error) {
+	pattern := "%" + match + "%"
+	rows, err := s.db.Query(
+		`SELECT id, username, password, role, encryption_key_id, email, permission, is_active, created_at, updated_at,
+		        COUNT(*) OVER() AS total
+		 FROM users WHERE username ILIKE $1 ORDER BY id LIMIT $2 OFFSET $3`,
+		pattern, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("分页查询用户列表时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	var total int
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID, &user.Username, &user.Password, &user.Role, &user.EncryptionKeyID,
+			&user.Email, &user.Permission, &user.IsActive, &user.CreatedAt, &user.UpdatedAt, &total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("扫描分页用户列表时出错: %w", err)
+		}
+		users = append(users, &user)
+	}
+	return users, total, rows.Err()
+}
+
+// UpdateUser 更新用户的可变基础信息（邮箱、角色、权限位掩码），并刷新updated_at
+//   - user: 待更新的用户信息，按ID定位记录
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *UserStore) UpdateUser(user *models.User) error {
+	_, err := s.db.Exec(
+		`UPDATE users SET email = $1, role = $2, permission = $3, updated_at = now() WHERE id = $4`,
+		user.Email, user.Role, user.Permission, user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新用户信息时出错: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserByID 根据用户ID删除用户
+//   - id: 要删除的用户ID
+//   - error: 如果删除过程中发生错误，返回相应的错误信息
+func (s *UserStore) DeleteUserByID(id int) error {
+	_, err := s.db.Exec("DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("删除用户时出错: %w", err)
+	}
+	return nil
+}
+
+// ResetPwd 管理员重置指定用户的密码，行为上等同于UpdateUserPassword，但以用户ID定位
+//   - id: 要重置密码的用户ID
+//   - newPassword: 新的明文密码
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *UserStore) ResetPwd(id int, newPassword string) error {
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("在对新密码进行哈希处理时出错: %w", err)
+	}
+
+	_, err = s.db.Exec("UPDATE users SET password = $1, updated_at = now() WHERE id = $2", hashedPassword, id)
+	if err != nil {
+		return fmt.Errorf("重置用户密码时出错: %w", err)
+	}
+	return nil
+}
+
+// setUserActive 更新指定用户的启用状态，被禁用的用户无法通过登录接口获得新的访问令牌
+func (s *UserStore) setUserActive(id int, active bool) error {
+	_, err := s.db.Exec("UPDATE users SET is_active = $1, updated_at = now() WHERE id = $2", active, id)
+	if err != nil {
+		return fmt.Errorf("更新用户启用状态时出错: %w", err)
+	}
+	return nil
+}
+
+// EnableUser 启用指定用户
+//   - id: 要启用的用户ID
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *UserStore) EnableUser(id int) error {
+	return s.setUserActive(id, true)
+}
+
+// DisableUser 禁用指定用户
+//   - id: 要禁用的用户ID
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+func (s *UserStore) DisableUser(id int) error {
+	return s.setUserActive(id, false)
+}
+
+// UpdateUserRole 更新指定用户的角色，用于管理员分配权限
+//   - username: 要更新角色的用户名
+//   - role: 新角色，取值为admin/uploader/viewer
+//   - error: 如果更新过程中发生错误，返回相应的错误信息
+// UpdateUserRole 同时将permission重置为models.DefaultPermission(role)，而不是只更新role列：
+// 管理端接口同时受Casbin（按role做路径级门禁）和authService.RequireAuth（按permission位掩码做
+// 细粒度能力校验）双重约束，两者若不同步，提升角色后permission仍停留在旧值，会导致新管理员
+// 在所有管理接口上都被RequireAuth拒绝
+func (s *UserStore) UpdateUserRole(username, role string) error {
+	_, err := s.db.Exec(
+		"UPDATE users SET role = $1, permission = $2 WHERE username = $3",
+		role, models.DefaultPermission(role), username,
+	)
+	if err != nil {
+		return fmt.Errorf("更新用户角色时出错: %w", err)
+	}
+	return nil
+}
+
+// generateOpaqueToken 生成一个URL安全的随机不透明令牌
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,89 @@
+// package encryption 封装了按用户派生服务端加密密钥、以及为上传/下载构造加密选项的逻辑
+package encryption
+
+import (
+	"crypto/sha256" // HKDF使用的哈希函数
+	"fmt"           // 格式化输出
+	"io"            // 用于从HKDF读取派生出的密钥
+
+	"golang.org/x/crypto/hkdf" // HKDF密钥派生
+
+	"github.com/minio/minio-go/v7/pkg/encrypt" // MinIO服务端加密选项
+
+	"github.com/sulibao/knowledge/internal/config" // 配置管理
+)
+
+// 支持的加密模式
+const (
+	ModeNone   = "none"
+	ModeSSES3  = "sse-s3"
+	ModeSSEC   = "sse-c"
+	ModeSSEKMS = "sse-kms"
+)
+
+// DeriveCustomerKey 基于主密钥、用户ID和用户当前的加密密钥版本号（encryption_key_id）
+// 通过HKDF-SHA256派生出该用户专属的32字节SSE-C客户密钥
+// 加入keyID作为派生信息的一部分，使得密钥轮换（更换keyID）能够产生全新的密钥
+//   - masterSecret: Config.Minio.Encryption.CustomerKeySource中配置的主密钥
+//   - userID: 用户ID
+//   - keyID: 用户当前的加密密钥版本号，对应users.encryption_key_id
+//   - [32]byte: 派生出的客户密钥
+//   - error: 如果派生过程中发生错误，返回相应的错误信息
+func DeriveCustomerKey(masterSecret string, userID int, keyID string) ([32]byte, error) {
+	var key [32]byte
+	info := []byte(fmt.Sprintf("user:%d:key:%s", userID, keyID))
+	kdf := hkdf.New(sha256.New, []byte(masterSecret), nil, info)
+	if _, err := io.ReadFull(kdf, key[:]); err != nil {
+		return key, fmt.Errorf("派生用户加密密钥时出错: %w", err)
+	}
+	return key, nil
+}
+
+// ForUpload 根据配置的加密模式，为上传操作构造MinIO服务端加密选项
+// 当用户的encryption_key_id为空但配置要求sse-c时，说明密钥材料缺失，返回错误
+//   - cfg: 应用配置信息
+//   - userID: 上传者的用户ID
+//   - keyID: 上传者当前的加密密钥版本号
+//   - username: 上传者的用户名，作为sse-kms的加密上下文
+//   - encrypt.ServerSide: 加密选项，none模式下返回nil
+//   - error: 如果密钥派生失败或密钥材料缺失，返回相应的错误信息
+func ForUpload(cfg *config.Config, userID int, keyID, username string) (encrypt.ServerSide, error) {
+	switch cfg.Minio.Encryption.Mode {
+	case ModeSSES3:
+		return encrypt.NewSSE(), nil
+	case ModeSSEC:
+		if keyID == "" {
+			return nil, fmt.Errorf("用户缺少加密密钥材料(encryption_key_id)")
+		}
+		key, err := DeriveCustomerKey(cfg.Minio.Encryption.CustomerKeySource, userID, keyID)
+		if err != nil {
+			return nil, err
+		}
+		return encrypt.NewSSEC(key[:])
+	case ModeSSEKMS:
+		return encrypt.NewSSEKMS(cfg.Minio.Encryption.KMSKeyID, map[string]interface{}{"username": username})
+	default:
+		return nil, nil // none：不加密
+	}
+}
+
+// ForDownload 根据配置的加密模式，为下载操作构造MinIO服务端加密选项
+// 只有sse-c需要在下载时重新提供客户密钥；sse-s3/sse-kms由MinIO在服务端自动处理，无需额外选项
+//   - cfg: 应用配置信息
+//   - userID: 下载者（即原上传者）的用户ID
+//   - keyID: 下载者当前的加密密钥版本号
+//   - encrypt.ServerSide: 加密选项，none/sse-s3/sse-kms模式下返回nil
+//   - error: 如果密钥派生失败或密钥材料缺失，返回相应的错误信息
+func ForDownload(cfg *config.Config, userID int, keyID string) (encrypt.ServerSide, error) {
+	if cfg.Minio.Encryption.Mode != ModeSSEC {
+		return nil, nil
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("用户缺少加密密钥材料(encryption_key_id)")
+	}
+	key, err := DeriveCustomerKey(cfg.Minio.Encryption.CustomerKeySource, userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt.NewSSEC(key[:])
+}
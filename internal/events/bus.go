@@ -0,0 +1,79 @@
+// package events 提供对象存储事件（创建/删除）的发布订阅总线，
+// 以及基于MinIO桶通知机制的事件监听、协调扫描与一组可插拔的内置处理器
+package events
+
+import (
+	"context" // 用于控制事件处理的上下文
+	"log"     // 用于日志记录
+	"sync"    // 用于保护处理器列表的并发访问
+)
+
+// Event 描述一次对象存储事件
+type Event struct {
+	Type        string // 事件类型，如s3:ObjectCreated:Put、s3:ObjectRemoved:Delete
+	Bucket      string // 所属存储桶
+	Key         string // 对象键（文件名）
+	Size        int64  // 对象大小（字节），ObjectRemoved事件下通常为0
+	ETag        string // 对象ETag
+	ContentType string // 对象内容类型，ObjectRemoved事件下通常为空
+	Uploader    string // 上传者用户名，来自对象的用户自定义元数据，取不到时为空
+}
+
+// Handler 是事件处理器的函数签名
+// 每个通过Subscribe注册的处理器都会收到EventBus发布的全部事件，需自行按Type过滤感兴趣的事件
+type Handler func(ctx context.Context, event Event)
+
+// EventBus 定义了事件的订阅与发布能力
+type EventBus interface {
+	Subscribe(handler Handler)
+	Publish(ctx context.Context, event Event)
+}
+
+// Dispatcher 是EventBus的默认实现，按注册顺序记录处理器
+// 同一事件的处理器按注册顺序在同一个goroutine中依次串行执行：main.go中NewIndexHandler注册在
+// NewChecksumHandler/NewContentExtractionHandler之前，保证files表的INSERT先于它们各自的
+// UPDATE ... WHERE name=$key提交，否则索引行尚未写入时，这些按name匹配的UPDATE会静默影响0行，
+// 导致sha256去重和正文提取的结果丢失。不同事件之间仍各自在独立的goroutine中处理，
+// 因此单个事件处理链的阻塞或panic不会影响其它事件，也不会拖慢桶通知监听循环
+type Dispatcher struct {
+	mu       sync.Mutex
+	handlers []Handler
+}
+
+// NewDispatcher 创建并返回一个新的Dispatcher实例
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Subscribe 注册一个事件处理器
+//   - handler: 待注册的事件处理器
+func (d *Dispatcher) Subscribe(handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, handler)
+}
+
+// Publish 将事件分发给所有已注册的处理器
+// 该事件的全部处理器在同一个goroutine中按注册顺序依次执行（见Dispatcher文档），
+// 彼此之间没有并发，因此依赖先后关系的处理器（如先索引、后补充sha256/正文）不会出现竞态
+//   - ctx: 处理过程中使用的上下文
+//   - event: 待分发的事件
+func (d *Dispatcher) Publish(ctx context.Context, event Event) {
+	d.mu.Lock()
+	handlers := make([]Handler, len(d.handlers))
+	copy(handlers, d.handlers)
+	d.mu.Unlock()
+
+	go func() {
+		for _, handler := range handlers {
+			func(h Handler) {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("Recovered from panic in event handler for %s: %v", event.Key, r)
+					}
+				}()
+				h(ctx, event)
+			}(handler)
+		}
+	}()
+}
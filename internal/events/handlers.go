@@ -0,0 +1,237 @@
+package events
+
+import (
+	"bytes"         // 用于构造Webhook请求体
+	"context"       // 用于控制处理过程的上下文
+	"crypto/hmac"   // 用于对Webhook负载签名
+	"crypto/sha256" // 用于计算对象内容摘要和Webhook签名
+	"encoding/hex"  // 用于摘要/签名的十六进制编码
+	"encoding/json" // 用于构造Webhook负载
+	"errors"        // 用于识别ErrDuplicateContent
+	"fmt"           // 用于构造错误信息
+	"io"            // 用于流式读取对象内容
+	"log"           // 用于日志记录
+	"net/http"      // 用于投递Webhook请求
+	"strings"       // 用于按前缀判断事件类型
+	"time"          // 用于Webhook HTTP客户端的超时设置
+
+	"github.com/minio/minio-go/v7"             // MinIO客户端
+	"github.com/minio/minio-go/v7/pkg/encrypt" // 服务端加密选项类型
+
+	"github.com/sulibao/knowledge/internal/config"             // 配置管理
+	"github.com/sulibao/knowledge/internal/database"           // 文件索引存储
+	"github.com/sulibao/knowledge/internal/encryption"         // 按加密模式构造服务端加密选项
+	"github.com/sulibao/knowledge/internal/extract"            // 可插拔的正文内容提取器
+	minio_client "github.com/sulibao/knowledge/internal/minio" // 持有可热重载的MinIO客户端
+	"github.com/sulibao/knowledge/internal/models"             // 数据模型
+)
+
+// sseForUploader 为读取uploader名下对象构造服务端加密选项
+// sse-c下解密密钥必须按对象上传者（而非处理事件的本进程身份）派生，与DownloadFile的取法一致：
+// Dispatcher保证同一事件的处理器按注册顺序串行执行，NewIndexHandler先于本处理器运行，
+// 此时event.Uploader已经是索引写入时使用的值，可直接信任
+//   - cfg: 应用配置，决定加密模式与密钥派生材料
+//   - userStore: 用于按用户名查询上传者的加密密钥版本号
+//   - uploader: 对象的上传者用户名，为空时视为找不到上传者
+func sseForUploader(cfg *config.Config, userStore *database.UserStore, uploader string) (encrypt.ServerSide, error) {
+	if cfg.Minio.Encryption.Mode != encryption.ModeSSEC {
+		return nil, nil
+	}
+	if uploader == "" {
+		return nil, fmt.Errorf("对象缺少上传者信息，无法派生解密密钥")
+	}
+	user, err := userStore.GetUserByUsername(uploader)
+	if err != nil {
+		return nil, fmt.Errorf("查询上传者 %s 时出错: %w", uploader, err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("上传者 %s 不存在，无法派生解密密钥", uploader)
+	}
+	return encryption.ForDownload(cfg, user.ID, user.EncryptionKeyID)
+}
+
+// isObjectCreated 判断事件类型是否属于对象创建类（含正常上传和协调扫描补发的创建事件）
+func isObjectCreated(eventType string) bool {
+	return strings.HasPrefix(eventType, "s3:ObjectCreated:")
+}
+
+// isObjectRemoved 判断事件类型是否属于对象删除类
+func isObjectRemoved(eventType string) bool {
+	return strings.HasPrefix(eventType, "s3:ObjectRemoved:")
+}
+
+// NewIndexHandler 返回一个将对象元数据写入/移出Postgres files表的事件处理器
+// ListFiles等读路径依赖该索引而非直接扫描MinIO，大幅提升大存储桶下的响应速度
+//   - fileStore: 文件索引存储
+//   - Handler: 可直接注册到EventBus的事件处理器
+func NewIndexHandler(fileStore *database.FileStore) Handler {
+	return func(ctx context.Context, event Event) {
+		switch {
+		case isObjectCreated(event.Type):
+			file := &models.File{
+				Name:        event.Key,
+				Size:        event.Size,
+				ETag:        event.ETag,
+				ContentType: event.ContentType,
+				Uploader:    event.Uploader,
+			}
+			if err := fileStore.UpsertFile(file); err != nil {
+				log.Printf("Error indexing object %s: %v", event.Key, err)
+			}
+		case isObjectRemoved(event.Type):
+			if err := fileStore.DeleteFileByName(event.Key); err != nil {
+				log.Printf("Error removing index entry for %s: %v", event.Key, err)
+			}
+		}
+	}
+}
+
+// NewChecksumHandler 返回一个处理器：对新建对象流式计算一次sha256摘要并写入索引
+// 若该摘要已属于另一个已索引的对象（即内容重复），则删除本次新建的重复对象及其索引记录
+//   - minioPool: 可热重载的MinIO客户端池，每次事件处理都取一次最新客户端，用于读取对象内容和删除重复对象
+//   - bucket: 对象所在的存储桶
+//   - fileStore: 文件索引存储
+//   - cfg: 应用配置，sse-c模式下用于按上传者派生解密密钥
+//   - userStore: 用于按上传者用户名查询其加密密钥版本号
+//   - Handler: 可直接注册到EventBus的事件处理器
+func NewChecksumHandler(minioPool *minio_client.Pool, bucket string, fileStore *database.FileStore, cfg *config.Config, userStore *database.UserStore) Handler {
+	return func(ctx context.Context, event Event) {
+		if !isObjectCreated(event.Type) {
+			return
+		}
+
+		sse, err := sseForUploader(cfg, userStore, event.Uploader)
+		if err != nil {
+			log.Printf("Error deriving decryption key for %s: %v", event.Key, err)
+			return
+		}
+
+		minioClient := minioPool.Client()
+		object, err := minioClient.GetObject(ctx, bucket, event.Key, minio.GetObjectOptions{ServerSideEncryption: sse})
+		if err != nil {
+			log.Printf("Error opening object %s for checksum: %v", event.Key, err)
+			return
+		}
+		defer object.Close()
+
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, object); err != nil {
+			log.Printf("Error streaming object %s for checksum: %v", event.Key, err)
+			return
+		}
+		sum := hex.EncodeToString(hasher.Sum(nil))
+
+		err = fileStore.SetSHA256(event.Key, sum)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, database.ErrDuplicateContent) {
+			log.Printf("Error writing checksum for %s: %v", event.Key, err)
+			return
+		}
+
+		log.Printf("Duplicate content detected for %s (sha256=%s), removing duplicate object", event.Key, sum)
+		if rmErr := minioClient.RemoveObject(ctx, bucket, event.Key, minio.RemoveObjectOptions{}); rmErr != nil {
+			log.Printf("Error removing duplicate object %s: %v", event.Key, rmErr)
+		}
+		if rmErr := fileStore.DeleteFileByName(event.Key); rmErr != nil {
+			log.Printf("Error removing index entry for duplicate object %s: %v", event.Key, rmErr)
+		}
+	}
+}
+
+// NewContentExtractionHandler 返回一个处理器：对支持的文本类文件（txt/md/csv，pdf暂不支持）
+// 提取其正文纯文本并写入files表的content_text字段，使全文检索能够匹配文档正文而不仅是文件名
+//   - minioPool: 可热重载的MinIO客户端池，每次事件处理都取一次最新客户端，用于读取对象内容
+//   - bucket: 对象所在的存储桶
+//   - fileStore: 文件索引存储
+//   - cfg: 应用配置，sse-c模式下用于按上传者派生解密密钥
+//   - userStore: 用于按上传者用户名查询其加密密钥版本号
+//   - Handler: 可直接注册到EventBus的事件处理器
+func NewContentExtractionHandler(minioPool *minio_client.Pool, bucket string, fileStore *database.FileStore, cfg *config.Config, userStore *database.UserStore) Handler {
+	return func(ctx context.Context, event Event) {
+		if !isObjectCreated(event.Type) {
+			return
+		}
+
+		extractor := extract.ForFilename(event.Key)
+		if extractor == nil {
+			return
+		}
+
+		sse, err := sseForUploader(cfg, userStore, event.Uploader)
+		if err != nil {
+			log.Printf("Error deriving decryption key for %s: %v", event.Key, err)
+			return
+		}
+
+		object, err := minioPool.Client().GetObject(ctx, bucket, event.Key, minio.GetObjectOptions{ServerSideEncryption: sse})
+		if err != nil {
+			log.Printf("Error opening object %s for content extraction: %v", event.Key, err)
+			return
+		}
+		defer object.Close()
+
+		text, err := extractor.Extract(ctx, object)
+		if err != nil {
+			log.Printf("Error extracting content from %s: %v", event.Key, err)
+			return
+		}
+
+		if err := fileStore.SetContentText(event.Key, text); err != nil {
+			log.Printf("Error storing extracted content for %s: %v", event.Key, err)
+		}
+	}
+}
+
+// webhookPayload 是投递给外部Webhook的事件负载
+type webhookPayload struct {
+	Type   string `json:"type"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag"`
+}
+
+// NewWebhookHandler 返回一个处理器：将每个事件以HMAC-SHA256签名的JSON负载投递给配置的Webhook URL
+// 签名通过X-Signature请求头携带，取值为十六进制编码的HMAC-SHA256(body, secret)，供接收方校验请求来源
+//   - urls: Webhook地址列表，为空时处理器不执行任何操作
+//   - secret: 用于签名的密钥
+//   - Handler: 可直接注册到EventBus的事件处理器
+func NewWebhookHandler(urls []string, secret string) Handler {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(ctx context.Context, event Event) {
+		if len(urls) == 0 {
+			return
+		}
+
+		body, err := json.Marshal(webhookPayload{
+			Type: event.Type, Bucket: event.Bucket, Key: event.Key, Size: event.Size, ETag: event.ETag,
+		})
+		if err != nil {
+			log.Printf("Error marshaling webhook payload for %s: %v", event.Key, err)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature := hex.EncodeToString(mac.Sum(nil))
+
+		for _, url := range urls {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+			if err != nil {
+				log.Printf("Error building webhook request to %s: %v", url, err)
+				continue
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Signature", signature)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Printf("Error delivering webhook to %s: %v", url, err)
+				continue
+			}
+			resp.Body.Close()
+		}
+	}
+}
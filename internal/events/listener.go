@@ -0,0 +1,172 @@
+package events
+
+import (
+	"context" // 用于控制监听循环和协调扫描的生命周期
+	"log"     // 用于日志记录
+	"time"    // 用于退避等待和协调扫描的定时器
+
+	"github.com/minio/minio-go/v7"                  // MinIO客户端
+	"github.com/minio/minio-go/v7/pkg/notification" // 桶通知记录的类型定义
+
+	minio_client "github.com/sulibao/knowledge/internal/minio" // 持有可热重载的MinIO客户端
+)
+
+// 桶通知断线重连时的指数退避参数
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 1 * time.Minute
+)
+
+// fileIndex 是Listener进行协调扫描时需要查询/修复的索引存储接口
+// 只依赖ListAllNames，Listener因此不绑定具体的存储类型，可传入*database.FileStore或其他实现
+type fileIndex interface {
+	ListAllNames() ([]string, error)
+}
+
+// Listener 订阅MinIO桶通知，将其转换为Event后发布到EventBus，并周期性执行协调扫描
+// 协调扫描用于修复监听断线期间错过的事件：多出的对象按创建事件补发，缺失的对象按删除事件补发
+type Listener struct {
+	MinioPool *minio_client.Pool // 可热重载的MinIO客户端池，每次订阅/扫描都通过Client()取用
+	Bus       EventBus
+	FileIndex fileIndex
+	Bucket    string
+}
+
+// NewListener 创建并返回一个新的Listener实例
+//   - minioPool: 可热重载的MinIO客户端池，用于订阅桶通知和执行协调扫描时的ListObjects
+//   - bus: 事件将被发布到的EventBus
+//   - fileIndex: 已索引对象名称的查询接口，通常传入*database.FileStore
+//   - bucket: 需要监听和扫描的存储桶名称
+func NewListener(minioPool *minio_client.Pool, bus EventBus, fileIndex fileIndex, bucket string) *Listener {
+	return &Listener{MinioPool: minioPool, Bus: bus, FileIndex: fileIndex, Bucket: bucket}
+}
+
+// Start 启动桶通知监听循环和周期性协调扫描，二者均随ctx取消而退出
+//   - ctx: 控制两个后台goroutine生命周期的上下文
+//   - reconcileInterval: 协调扫描的执行周期
+func (l *Listener) Start(ctx context.Context, reconcileInterval time.Duration) {
+	go l.listenLoop(ctx)
+	go l.reconcileLoop(ctx, reconcileInterval)
+}
+
+// listenLoop 持续订阅桶通知并分发事件，连接断开后按指数退避重新订阅
+// 每次（重新）订阅都从MinioPool取一次最新客户端，因此MinIO端点/凭证热重载后，最迟在下一次
+// 重连时即可生效；已建立的长连接本身不会被热重载提前打断
+func (l *Listener) listenLoop(ctx context.Context) {
+	backoff := initialBackoff
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		notificationCh := l.MinioPool.Client().ListenBucketNotification(
+			ctx, l.Bucket, "", "", []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"},
+		)
+
+		for info := range notificationCh {
+			if info.Err != nil {
+				log.Printf("Error from bucket notification stream: %v", info.Err)
+				continue
+			}
+			// 收到过消息说明连接是健康的，重置退避时长
+			backoff = initialBackoff
+			for _, record := range info.Records {
+				l.Bus.Publish(ctx, recordToEvent(record))
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Bucket notification stream disconnected, retrying in %v", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// reconcileLoop 按固定周期执行一次协调扫描，直到ctx被取消
+func (l *Listener) reconcileLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.reconcileOnce(ctx)
+		}
+	}
+}
+
+// reconcileOnce 对比存储桶的实际对象列表与已索引的对象名称，为二者的差集补发创建/删除事件
+// 用于修复listenLoop断线期间错过的桶通知
+func (l *Listener) reconcileOnce(ctx context.Context) {
+	indexedNames, err := l.FileIndex.ListAllNames()
+	if err != nil {
+		log.Printf("Error listing indexed file names during reconciliation: %v", err)
+		return
+	}
+	indexed := make(map[string]bool, len(indexedNames))
+	for _, name := range indexedNames {
+		indexed[name] = true
+	}
+
+	actual := make(map[string]bool, len(indexedNames))
+	objectsCh := l.MinioPool.Client().ListObjects(ctx, l.Bucket, minio.ListObjectsOptions{Recursive: true})
+	for object := range objectsCh {
+		if object.Err != nil {
+			log.Printf("Error listing objects during reconciliation: %v", object.Err)
+			return
+		}
+		actual[object.Key] = true
+		if !indexed[object.Key] {
+			log.Printf("Reconciliation: indexing object missed by notifications: %s", object.Key)
+			l.Bus.Publish(ctx, Event{
+				Type: "s3:ObjectCreated:Reconcile", Bucket: l.Bucket, Key: object.Key,
+				Size: object.Size, ETag: object.ETag, ContentType: object.ContentType,
+			})
+		}
+	}
+
+	for name := range indexed {
+		if !actual[name] {
+			log.Printf("Reconciliation: removing stale index entry for deleted object: %s", name)
+			l.Bus.Publish(ctx, Event{Type: "s3:ObjectRemoved:Reconcile", Bucket: l.Bucket, Key: name})
+		}
+	}
+}
+
+// uploaderMetadataKeys 列出uploader用户自定义元数据在桶通知中可能出现的键名
+// 上传时通过UserMetadata: map[string]string{"uploader": ...}设置该元数据，但不同MinIO版本/配置
+// 下回传给桶通知payload的键名大小写、是否携带x-amz-meta-前缀并不总是一致，这里逐一尝试常见形式，
+// 避免只匹配一种写法而让该字段经常取不到——它一旦为空，SSE-C模式下载会因找不到正确的
+// 客户密钥而对合法持有者返回409
+var uploaderMetadataKeys = []string{"uploader", "Uploader", "X-Amz-Meta-Uploader", "x-amz-meta-uploader"}
+
+// recordToEvent 将MinIO通知记录转换为内部Event
+func recordToEvent(record notification.Event) Event {
+	uploader := ""
+	for _, key := range uploaderMetadataKeys {
+		if v, ok := record.S3.Object.UserMetadata[key]; ok && v != "" {
+			uploader = v
+			break
+		}
+	}
+	return Event{
+		Type:        record.EventName,
+		Bucket:      record.S3.Bucket.Name,
+		Key:         record.S3.Object.Key,
+		Size:        record.S3.Object.Size,
+		ETag:        record.S3.Object.ETag,
+		ContentType: record.S3.Object.ContentType,
+		Uploader:    uploader,
+	}
+}
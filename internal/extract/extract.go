@@ -0,0 +1,54 @@
+// package extract 定义了从对象内容中提取可供全文检索的纯文本的可插拔接口
+// 由internal/events包中的内容提取事件处理器调用，提取结果写入files表的content_text字段
+package extract
+
+import (
+	"context"       // 用于控制提取过程的上下文
+	"fmt"           // 格式化输出
+	"io"            // 用于流式读取对象内容
+	"path/filepath" // 用于按扩展名选择提取器
+	"strings"       // 用于扩展名大小写归一化
+)
+
+// maxExtractBytes 是单次提取读取的最大字节数，避免为超大文本文件全量加载到内存
+const maxExtractBytes = 10 * 1024 * 1024 // 10MB
+
+// Extractor 从对象内容中提取纯文本
+type Extractor interface {
+	Extract(ctx context.Context, r io.Reader) (string, error)
+}
+
+// plainTextExtractor 适用于本身即为纯文本的格式（txt/md/csv），直接读取原文即可
+type plainTextExtractor struct{}
+
+func (plainTextExtractor) Extract(ctx context.Context, r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxExtractBytes))
+	if err != nil {
+		return "", fmt.Errorf("读取文本内容时出错: %w", err)
+	}
+	return string(data), nil
+}
+
+// pdfExtractor 目前尚未接入真正的PDF文本解析库（如pdfcpu），在引入该依赖之前始终返回空字符串
+// PDF文件仍可通过文件名和标签被检索到，只是暂不支持正文全文检索
+type pdfExtractor struct{}
+
+func (pdfExtractor) Extract(ctx context.Context, r io.Reader) (string, error) {
+	return "", nil
+}
+
+// byExtension 按文件扩展名（小写，含前导点）注册对应的提取器
+var byExtension = map[string]Extractor{
+	".txt": plainTextExtractor{},
+	".md":  plainTextExtractor{},
+	".csv": plainTextExtractor{},
+	".pdf": pdfExtractor{},
+}
+
+// ForFilename 根据文件名的扩展名返回对应的内容提取器，不支持的扩展名返回nil
+//   - name: 对象名称
+//   - Extractor: 对应的提取器，不支持的格式返回nil
+func ForFilename(name string) Extractor {
+	ext := strings.ToLower(filepath.Ext(name))
+	return byExtension[ext]
+}
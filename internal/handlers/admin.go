@@ -0,0 +1,378 @@
+// package handlers 包含处理HTTP请求的处理器函数
+package handlers
+
+import (
+	"context"       // 用于控制请求的上下文
+	"encoding/json" // 用于JSON编码和解码
+	"log"           // 用于日志记录
+	"net/http"      // 提供HTTP客户端和服务器实现
+	"strconv"       // 用于解析用户列表接口的分页参数
+
+	"github.com/minio/minio-go/v7"             // MinIO客户端
+	"github.com/minio/minio-go/v7/pkg/encrypt" // 服务端加密选项类型
+
+	"github.com/sulibao/knowledge/internal/config"             // 配置管理
+	"github.com/sulibao/knowledge/internal/database"           // 导入数据库操作相关包
+	"github.com/sulibao/knowledge/internal/encryption"         // 服务端加密选项构造
+	minio_client "github.com/sulibao/knowledge/internal/minio" // 持有可热重载的MinIO客户端
+	"github.com/sulibao/knowledge/internal/models"             // 导入数据模型相关包
+)
+
+// 用户列表接口的分页参数默认值与上限
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// AdminHandler 结构体处理管理员专属的用户与角色管理请求
+// 所有路由都应配合middleware.AuthorizeRequired限制为admin角色才能访问
+type AdminHandler struct {
+	UserStore       *database.UserStore       // 用户存储接口，用于用户数据的CRUD操作
+	PermissionStore *database.PermissionStore // 权限位掩码存储接口，用于在角色之外单独调整能力位
+	FileStore       *database.FileStore       // 文件索引存储，密钥轮换时用于按上传者筛选需要重新加密的对象
+	MinioPool       *minio_client.Pool        // MinIO客户端池，密钥轮换时用于重新加密已存储的对象，每次操作都通过Client()取用以使热重载生效
+	Config          *config.Config            // 应用配置信息，用于读取当前的加密模式
+}
+
+// NewAdminHandler 创建并返回一个新的AdminHandler实例
+//   - userStore: 用户存储接口，用于访问用户数据
+//   - permissionStore: 权限位掩码存储接口
+//   - fileStore: 文件索引存储，用于密钥轮换时按上传者筛选对象
+//   - minioPool: 可热重载的MinIO客户端池，用于密钥轮换时重新加密对象
+//   - cfg: 应用配置信息
+//   - *AdminHandler: 新创建的AdminHandler实例
+func NewAdminHandler(userStore *database.UserStore, permissionStore *database.PermissionStore, fileStore *database.FileStore, minioPool *minio_client.Pool, cfg *config.Config) *AdminHandler {
+	return &AdminHandler{UserStore: userStore, PermissionStore: permissionStore, FileStore: fileStore, MinioPool: minioPool, Config: cfg}
+}
+
+// minioClient 返回当前生效的MinIO客户端，每次调用都从MinioPool取最新实例，而不是缓存一份，
+// 使端点/凭证热重载后发起的密钥轮换请求能够用上新的客户端
+func (h *AdminHandler) minioClient() *minio.Client {
+	return h.MinioPool.Client()
+}
+
+// userView 是对外暴露的用户信息视图，不包含密码哈希
+type userView struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Email      string `json:"email"`
+	Permission int    `json:"permission"`
+	IsActive   bool   `json:"isActive"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+func toUserView(u *models.User) userView {
+	return userView{
+		ID: u.ID, Username: u.Username, Role: u.Role, Email: u.Email,
+		Permission: u.Permission, IsActive: u.IsActive,
+		CreatedAt: u.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: u.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// listUsersResponse 是GET /api/admin/users的响应体
+type listUsersResponse struct {
+	Users []userView `json:"users"`
+	Page  int        `json:"page"`
+	Total int        `json:"total"`
+}
+
+// ListUsers 处理分页获取用户列表的请求，支持按用户名模糊匹配
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(q.Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultUserPageSize
+	}
+	if pageSize > maxUserPageSize {
+		pageSize = maxUserPageSize
+	}
+
+	users, total, err := h.UserStore.FindAll((page-1)*pageSize, pageSize, q.Get("match"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "获取用户列表失败", "message": err.Error()})
+		return
+	}
+
+	views := make([]userView, 0, len(users))
+	for _, u := range users {
+		views = append(views, toUserView(u))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listUsersResponse{Users: views, Page: page, Total: total})
+}
+
+// DeleteUser 处理删除指定用户的请求
+//   - w: HTTP响应写入器
+//   - r: HTTP请求（查询参数携带id）
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "用户ID不合法"})
+		return
+	}
+
+	if err := h.UserStore.DeleteUserByID(id); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "删除用户失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "用户删除成功"})
+}
+
+// resetPasswordRequest 是重置密码接口的请求体
+type resetPasswordRequest struct {
+	ID          int    `json:"id"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ResetPassword 处理管理员重置指定用户密码的请求
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *AdminHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 || req.NewPassword == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败或参数缺失"})
+		return
+	}
+
+	if err := h.UserStore.ResetPwd(req.ID, req.NewPassword); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "重置密码失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "密码重置成功"})
+}
+
+// setUserStatusRequest 是启用/禁用用户接口的请求体
+type setUserStatusRequest struct {
+	ID     int  `json:"id"`
+	Active bool `json:"active"`
+}
+
+// SetUserStatus 处理启用或禁用指定用户的请求
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *AdminHandler) SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req setUserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败或参数缺失"})
+		return
+	}
+
+	var err error
+	if req.Active {
+		err = h.UserStore.EnableUser(req.ID)
+	} else {
+		err = h.UserStore.DisableUser(req.ID)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "更新用户启用状态失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "用户启用状态更新成功"})
+}
+
+// updateRoleRequest 是更新用户角色接口的请求体
+type updateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateUserRole 处理调整指定用户角色绑定的请求
+//   - w: HTTP响应写入器
+//   - r: HTTP请求（查询参数携带username）
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "用户名不能为空"})
+		return
+	}
+
+	var req updateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败", "message": err.Error()})
+		return
+	}
+
+	switch req.Role {
+	case models.RoleAdmin, models.RoleUploader, models.RoleViewer:
+		// 合法角色
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "不支持的角色: " + req.Role})
+		return
+	}
+
+	if err := h.UserStore.UpdateUserRole(username, req.Role); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "更新用户角色失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "用户角色更新成功"})
+}
+
+// rotateKeyRequest 是密钥轮换接口的请求体
+type rotateKeyRequest struct {
+	Username string `json:"username"`
+}
+
+// RotateKey 为指定用户轮换SSE-C客户密钥，并就地重新加密该用户名下（按文件索引的uploader筛选）
+// 的全部对象。只有当每个对象都成功重新加密后，才会把新密钥版本号写回数据库；
+// 期间若有任何对象重加密失败，会尝试把已经成功的对象回滚到旧密钥下，并保持数据库中的密钥版本号
+// 不变，避免出现"部分对象已用新密钥加密，但数据库仍记录旧版本号（或反之）"的不一致状态
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *AdminHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req rotateKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败或用户名为空"})
+		return
+	}
+
+	if h.Config.Minio.Encryption.Mode != encryption.ModeSSEC {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "当前加密模式下无需轮换客户密钥"})
+		return
+	}
+
+	user, err := h.UserStore.GetUserByUsername(req.Username)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "用户不存在"})
+		return
+	}
+	oldKeyID := user.EncryptionKeyID
+
+	newKeyID, err := database.GenerateEncryptionKeyID()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "生成新密钥版本号失败", "message": err.Error()})
+		return
+	}
+
+	oldKey, err := encryption.DeriveCustomerKey(h.Config.Minio.Encryption.CustomerKeySource, user.ID, oldKeyID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "派生旧密钥失败", "message": err.Error()})
+		return
+	}
+	newKey, err := encryption.DeriveCustomerKey(h.Config.Minio.Encryption.CustomerKeySource, user.ID, newKeyID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "派生新密钥失败", "message": err.Error()})
+		return
+	}
+	srcSSE, err := encrypt.NewSSEC(oldKey[:])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "构造源加密选项失败", "message": err.Error()})
+		return
+	}
+	dstSSE, err := encrypt.NewSSEC(newKey[:])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "构造目标加密选项失败", "message": err.Error()})
+		return
+	}
+
+	names, err := h.FileStore.ListNamesByUploader(req.Username)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "按上传者查询对象列表失败", "message": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	var reEncrypted []string
+	var failedObject string
+	for _, name := range names {
+		src := minio.CopySrcOptions{Bucket: h.Config.Minio.BucketName, Object: name, Encryption: srcSSE}
+		dst := minio.CopyDestOptions{Bucket: h.Config.Minio.BucketName, Object: name, Encryption: dstSSE}
+		if _, err := h.minioClient().CopyObject(ctx, dst, src); err != nil {
+			log.Printf("Error re-encrypting object %s during key rotation for %s: %v", name, req.Username, err)
+			failedObject = name
+			break
+		}
+		reEncrypted = append(reEncrypted, name)
+	}
+
+	if failedObject != "" {
+		unrecovered := h.rollbackReEncryption(ctx, reEncrypted, dstSSE, srcSSE)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "对象 " + failedObject + " 重新加密失败，密钥版本号未变更",
+			"unrecovered": unrecovered,
+		})
+		return
+	}
+
+	if err := h.UserStore.SetEncryptionKeyID(req.Username, newKeyID); err != nil {
+		unrecovered := h.rollbackReEncryption(ctx, reEncrypted, dstSSE, srcSSE)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "持久化新密钥版本号失败，已尝试回滚重加密的对象",
+			"message":     err.Error(),
+			"unrecovered": unrecovered,
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "密钥轮换完成",
+		"reEncrypted": len(reEncrypted),
+	})
+}
+
+// rollbackReEncryption 在密钥轮换中途失败时，尝试把已经重新加密过的对象用dstSSE→srcSSE
+// 的反向CopyObject复原为轮换前的加密状态，使数据库中未变更的密钥版本号与对象的实际加密状态保持一致
+// 返回无法复原的对象名称列表，供调用方记录以便人工介入
+func (h *AdminHandler) rollbackReEncryption(ctx context.Context, names []string, currentSSE, revertToSSE encrypt.ServerSide) []string {
+	var unrecovered []string
+	for _, name := range names {
+		src := minio.CopySrcOptions{Bucket: h.Config.Minio.BucketName, Object: name, Encryption: currentSSE}
+		dst := minio.CopyDestOptions{Bucket: h.Config.Minio.BucketName, Object: name, Encryption: revertToSSE}
+		if _, err := h.minioClient().CopyObject(ctx, dst, src); err != nil {
+			log.Printf("Error rolling back re-encrypted object %s: %v", name, err)
+			unrecovered = append(unrecovered, name)
+		}
+	}
+	return unrecovered
+}
@@ -6,30 +6,34 @@ import (
 	"log"           // 用于日志记录
 	"net/http"      // 提供HTTP客户端和服务器实现
 
+	"github.com/sulibao/knowledge/internal/config"     // 导入配置包，用于读取JWT密钥和有效期
 	"github.com/sulibao/knowledge/internal/database"   // 导入数据库操作相关包
 	"github.com/sulibao/knowledge/internal/middleware" // 导入中间件相关包
 	"github.com/sulibao/knowledge/internal/models"     // 导入数据模型相关包
-
-	"golang.org/x/crypto/bcrypt" // 用于密码哈希和验证
+	"github.com/sulibao/knowledge/internal/password"   // 按编码哈希前缀分派的密码校验
 )
 
 // AuthHandler 结构体处理与用户认证相关的HTTP请求
 type AuthHandler struct {
 	UserStore *database.UserStore // 用户存储接口，用于用户数据的CRUD操作
+	Config    *config.Config      // 应用配置信息，提供令牌有效期
+	AuthKeys  middleware.KeySet   // 签发访问令牌使用的密钥材料及算法，与middleware.InitAuth保持一致
 }
 
 // NewAuthHandler 创建并返回一个新的AuthHandler实例
 //   - userStore: 用户存储接口，用于访问用户数据
+//   - cfg: 应用配置信息
+//   - authKeys: 签发访问令牌使用的密钥材料及算法，通常与main中传给middleware.InitAuth的值相同
 //   - *AuthHandler: 新创建的AuthHandler实例
-func NewAuthHandler(userStore *database.UserStore) *AuthHandler {
-	return &AuthHandler{UserStore: userStore}
+func NewAuthHandler(userStore *database.UserStore, cfg *config.Config, authKeys middleware.KeySet) *AuthHandler {
+	return &AuthHandler{UserStore: userStore, Config: cfg, AuthKeys: authKeys}
 }
 
 // Register 处理用户注册请求
 //   - 解析请求体中的用户信息
 //   - 验证用户名和密码是否为空
 //   - 检查用户名是否已存在
-//   - 创建新用户并返回结果
+//   - 创建新用户（角色固定为viewer，管理员可后续通过管理接口调整）并返回结果
 //   - w: HTTP响应写入器
 //   - r: HTTP请求
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +68,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 自助注册的用户统一赋予viewer角色，更高权限由管理员通过用户管理接口调整
+	user.Role = models.RoleViewer
+
 	// 创建新用户
 	err = h.UserStore.CreateUser(&user)
 	if err != nil {
@@ -78,11 +85,19 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully"})
 }
 
+// loginResponse 是登录和刷新接口统一返回的令牌信息
+type loginResponse struct {
+	Message      string `json:"message"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	Role         string `json:"role"`
+}
+
 // Login 处理用户登录请求
 //   - 解析请求体中的用户登录信息
 //   - 验证用户是否存在
 //   - 验证密码是否正确
-//   - 创建会话并设置认证状态
+//   - 签发访问令牌和刷新令牌，并将访问令牌写入cookie供HTML页面使用
 //   - 返回登录结果
 //   - w: HTTP响应写入器
 //   - r: HTTP请求
@@ -112,34 +127,145 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 调试日志：记录登录尝试的用户名、数据库中的哈希密码和用户提供的明文密码
-	log.Printf("Attempting login for user: %s\n", user.Username)
-	log.Printf("Hashed password from DB: %s\n", existingUser.Password)
-	log.Printf("Plain password from user: %s\n", user.Password)
+	// 账号已被管理员禁用，拒绝签发新的令牌
+	if !existingUser.IsActive {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": "账号已被禁用，请联系管理员"})
+		return
+	}
 
-	// 使用bcrypt比较哈希密码和明文密码
-	err = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(user.Password))
+	// 按existingUser.Password的编码前缀选择对应的校验算法（$2a$/$2b$ → bcrypt，$argon2id$ → argon2id）
+	hasher := password.ForEncoded(existingUser.Password)
+	if hasher == nil {
+		log.Printf("Unrecognized password hash encoding for user %s", existingUser.Username)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	ok, needsRehash, err := hasher.Verify(user.Password, existingUser.Password)
 	if err != nil {
+		log.Printf("Error verifying password for user %s: %v", existingUser.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error"})
+		return
+	}
+	if !ok {
 		// 密码不匹配，返回401未授权错误
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"message": "登录失败，请检查用户名和密码！"})
 		return
 	}
 
-	// 获取会话并设置认证状态
-	session, _ := middleware.Store.Get(r, "session-name")
-	session.Values["authenticated"] = true             // 设置认证状态为true
-	session.Values["username"] = existingUser.Username // 保存用户名到会话
-	// 保存会话到响应
-	err = session.Save(r, w)
+	// hasher.Verify只能感知同算法内的参数漂移（如bcrypt cost降低），无法感知"目标算法已经从
+	// bcrypt切换为argon2id"这类跨算法迁移，因此这里额外比较该哈希的实际算法与当前配置的目标算法，
+	// 两者不一致时同样视为需要重新哈希——这正是bcrypt→argon2id透明迁移能够触发的关键一步
+	targetHasher := h.Config.PasswordHasher()
+	if hasher.Identity() != targetHasher.Identity() {
+		needsRehash = true
+	}
+
+	// 密码校验通过但哈希不符合当前策略（遗留算法、参数低于策略，或目标算法已变更），透明地按当前
+	// 算法重新哈希并写回
+	if needsRehash {
+		if err := h.UserStore.UpdateUserPassword(existingUser.Username, user.Password); err != nil {
+			log.Printf("Error rehashing password for user %s: %v", existingUser.Username, err)
+		}
+	}
+
+	accessToken, refreshToken, err := h.issueTokenPair(existingUser, r)
 	if err != nil {
-		// 记录会话保存错误，但继续处理
-		log.Printf("Error saving session: %v\n", err)
+		log.Printf("Error issuing token pair for %s: %v", existingUser.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error"})
+		return
 	}
-	// 记录登录后的会话认证状态
-	log.Printf("Session authenticated status after login: %v\n", session.Values["authenticated"])
+
+	// 将访问令牌写入cookie，使登录后直接跳转的HTML页面（如dashboard）无需携带Authorization头也能通过AuthRequired
+	http.SetCookie(w, &http.Cookie{
+		Name:     "access_token",
+		Value:    accessToken,
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   int(h.Config.AccessTokenTTLDuration().Seconds()),
+	})
 
 	// 登录成功，返回200状态码
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "登录成功"})
+	json.NewEncoder(w).Encode(loginResponse{
+		Message:      "登录成功",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Role:         existingUser.Role,
+	})
+}
+
+// Refresh 处理令牌刷新请求
+//   - 校验客户端提交的刷新令牌
+//   - 吊销旧的刷新令牌，签发新的访问令牌和刷新令牌（刷新令牌轮换，防止重放）
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "请求体解析失败", http.StatusBadRequest)
+		return
+	}
+
+	username, oldJTI, err := h.UserStore.ValidateRefreshToken(req.RefreshToken)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "刷新令牌无效或已过期"})
+		return
+	}
+
+	user, err := h.UserStore.GetUserByUsername(username)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "用户不存在"})
+		return
+	}
+
+	// 吊销已使用过的刷新令牌，避免同一张刷新令牌被重复使用
+	if err := h.UserStore.RevokeRefreshToken(oldJTI); err != nil {
+		log.Printf("Error revoking old refresh token for %s: %v", username, err)
+	}
+
+	accessToken, refreshToken, err := h.issueTokenPair(user, r)
+	if err != nil {
+		log.Printf("Error issuing token pair on refresh for %s: %v", username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginResponse{
+		Message:      "刷新成功",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Role:         user.Role,
+	})
+}
+
+// issueTokenPair 为指定用户签发一对访问令牌和刷新令牌
+// 两者共享同一个jti，使刷新令牌被吊销时，关联的访问令牌也能通过AuthRequired中的吊销检查被拒绝
+// r的User-Agent请求头和RemoteAddr随刷新令牌一并记录，仅用于会话审计（如用户查看"当前登录设备"列表）
+func (h *AuthHandler) issueTokenPair(user *models.User, r *http.Request) (accessToken string, refreshToken string, err error) {
+	refreshToken, jti, err := h.UserStore.IssueRefreshToken(
+		user.Username, h.Config.RefreshTokenTTLDuration(), r.UserAgent(), r.RemoteAddr,
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err = middleware.GenerateAccessToken(
+		user.Username, user.Role, h.AuthKeys, h.Config.AccessTokenTTLDuration(), jti,
+	)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
 }
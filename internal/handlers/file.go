@@ -9,26 +9,172 @@ import (
 	"io"            // 提供I/O原语
 	"log"           // 用于日志记录
 	"net/http"      // 提供HTTP客户端和服务器实现
+	"net/url"       // 用于构造预签名URL的查询参数
 	"path/filepath" // 用于处理文件路径
+	"strconv"       // 用于解析检索接口的分页与大小参数
 	"time"          // 用于时间相关操作
 
-	"github.com/minio/minio-go/v7"                 // MinIO客户端
-	"github.com/sulibao/knowledge/internal/config" // 配置管理
+	"github.com/gorilla/mux"       // 用于读取路径参数
+	"github.com/minio/minio-go/v7" // MinIO客户端
+
+	"github.com/sulibao/knowledge/internal/config"             // 配置管理
+	"github.com/sulibao/knowledge/internal/database"           // 数据库操作相关包，用于查询当前用户的加密密钥材料
+	"github.com/sulibao/knowledge/internal/encryption"         // 服务端加密选项构造
+	"github.com/sulibao/knowledge/internal/middleware"         // 用于从请求上下文中读取当前登录用户
+	minio_client "github.com/sulibao/knowledge/internal/minio" // 持有可热重载的MinIO客户端
+	"github.com/sulibao/knowledge/internal/models"             // 数据模型
 )
 
 // FileHandler 结构体用于处理文件相关的HTTP请求
 // 包含MinIO客户端和应用配置
 type FileHandler struct {
-	MinioClient *minio.Client  // MinIO客户端，用于对象存储操作
-	Config      *config.Config // 应用配置信息
+	MinioPool *minio_client.Pool  // MinIO客户端池，每次操作都通过Client()取用，使端点/凭证热重载对本处理器生效
+	Config    *config.Config      // 应用配置信息
+	UserStore *database.UserStore // 用户存储，用于获取当前用户的加密密钥材料
+	FileStore *database.FileStore // 文件索引存储，由桶事件处理器异步维护，ListFiles据此响应
 }
 
 // NewFileHandler 创建并返回一个新的FileHandler实例
-//   - minioClient: MinIO客户端实例，用于与对象存储交互
+//   - minioPool: MinIO客户端池，用于与对象存储交互，配置热重载后对本处理器新发起的请求立即生效
 //   - cfg: 应用配置信息
+//   - userStore: 用户存储，用于获取当前用户的加密密钥材料
+//   - fileStore: 文件索引存储，用于ListFiles读取对象元数据
 //   - 初始化后的FileHandler指针
-func NewFileHandler(minioClient *minio.Client, cfg *config.Config) *FileHandler {
-	return &FileHandler{MinioClient: minioClient, Config: cfg}
+func NewFileHandler(minioPool *minio_client.Pool, cfg *config.Config, userStore *database.UserStore, fileStore *database.FileStore) *FileHandler {
+	return &FileHandler{MinioPool: minioPool, Config: cfg, UserStore: userStore, FileStore: fileStore}
+}
+
+// minioClient 返回当前生效的MinIO客户端，每次调用都从MinioPool取最新实例，而不是缓存一份
+func (h *FileHandler) minioClient() *minio.Client {
+	return h.MinioPool.Client()
+}
+
+// currentUser 从请求上下文中解析出发起请求的用户记录
+func (h *FileHandler) currentUser(r *http.Request) (*models.User, error) {
+	username, ok := middleware.UsernameFromContext(r.Context())
+	if !ok {
+		return nil, nil
+	}
+	return h.UserStore.GetUserByUsername(username)
+}
+
+// presignSizeThreshold 是仪表盘在直传MinIO（预签名URL）和经服务端代理之间做选择的文件大小阈值
+// 超过该阈值时，前端应优先调用预签名接口，让浏览器直接与MinIO通信，而不是把文件流经Go进程
+const presignSizeThreshold = 32 * 1024 * 1024 // 32MB
+
+// PresignUploadRequest 是POST /api/presign/upload的请求体
+type PresignUploadRequest struct {
+	Filename string `json:"filename"` // 期望上传的文件名
+}
+
+// PresignResponse 是预签名接口统一返回的响应结构
+// 包含客户端直接访问MinIO所需的全部信息
+type PresignResponse struct {
+	URL        string            `json:"url"`               // 预签名URL
+	Method     string            `json:"method"`            // 客户端应使用的HTTP方法，如PUT、GET
+	Headers    map[string]string `json:"headers,omitempty"` // 客户端发起请求时需要携带的请求头
+	Expiry     time.Time         `json:"expiry"`            // URL过期时间
+	ObjectName string            `json:"objectName"`        // 最终存储在MinIO中的对象名称
+}
+
+// PresignUpload 处理预签名上传URL的申请请求
+// 返回一个短期有效的PUT URL，客户端可直接上传到MinIO，无需经过本服务中转
+// minio-go的PresignedPutObject不支持附带服务端加密相关的请求头/查询参数，因此客户端直传完全
+// 绕过了ForUpload为代理上传路径施加的sse-c/sse-kms逻辑：在加密模式不为none时拒绝签发预签名
+// URL，要求客户端改用会经过本服务中转、因而能够正确加密的/api/upload
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *FileHandler) PresignUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.Config.Minio.Encryption.Mode != encryption.ModeNone {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "当前已启用服务端加密，不支持预签名直传，请改用/api/upload"})
+		return
+	}
+
+	// 解析请求体，获取期望的文件名
+	var req PresignUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding presign upload request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败", "message": err.Error()})
+		return
+	}
+
+	if req.Filename == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "文件名不能为空"})
+		return
+	}
+
+	objectName := filepath.Base(req.Filename)
+	expiry := h.Config.PresignExpiryDuration()
+
+	ctx := context.Background()
+	// 生成预签名PUT URL，客户端可直接使用该URL上传文件内容
+	presignedURL, err := h.minioClient().PresignedPutObject(ctx, h.Config.Minio.BucketName, objectName, expiry)
+	if err != nil {
+		log.Printf("Error generating presigned upload URL: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "生成预签名上传地址失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PresignResponse{
+		URL:        presignedURL.String(),
+		Method:     http.MethodPut,
+		ObjectName: objectName,
+		Expiry:     time.Now().Add(expiry),
+	})
+}
+
+// PresignDownload 处理预签名下载URL的申请请求
+// 返回一个短期有效的GET URL，客户端可直接从MinIO下载文件，无需经过本服务中转
+// sse-c模式下解密需要客户端在请求中携带客户密钥请求头，而minio-go的PresignedGetObject不支持
+// 附带这些请求头（只能附带查询参数），预签名GET无法完成解密；因此加密模式不为none时拒绝签发，
+// 要求客户端改用会经过本服务中转、因而能够正确解密的/api/download
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *FileHandler) PresignDownload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.Config.Minio.Encryption.Mode != encryption.ModeNone {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "当前已启用服务端加密，不支持预签名直接下载，请改用/api/download"})
+		return
+	}
+
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "文件名不能为空"})
+		return
+	}
+
+	expiry := h.Config.PresignExpiryDuration()
+
+	// 设置Content-Disposition请求参数，使浏览器按原文件名下载
+	reqParams := make(url.Values)
+	reqParams.Set("response-content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	ctx := context.Background()
+	presignedURL, err := h.minioClient().PresignedGetObject(ctx, h.Config.Minio.BucketName, filename, expiry, reqParams)
+	if err != nil {
+		log.Printf("Error generating presigned download URL: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "生成预签名下载地址失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PresignResponse{
+		URL:        presignedURL.String(),
+		Method:     http.MethodGet,
+		ObjectName: filename,
+		Expiry:     time.Now().Add(expiry),
+	})
 }
 
 // UploadFile 处理文件上传请求
@@ -58,14 +204,16 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	log.Printf("File upload started: %s, size: %d bytes, content-type: %s",
 		header.Filename, header.Size, header.Header.Get("Content-Type"))
 
-	// 检查文件大小是否超过限制(1GB)
-	if header.Size > 1024*1024*1024 { // 1GB = 1024MB = 1024*1024KB = 1024*1024*1024B
+	// 检查文件大小是否超过配置的上限（Config.Minio.MaxObjectSize，默认5TB）
+	// 该接口走服务端中转，超大文件建议改用/api/uploads分片上传或预签名直传
+	maxObjectSize := h.Config.MaxObjectSizeBytes()
+	if header.Size > maxObjectSize {
 		// 记录文件过大的错误
 		log.Printf("文件过大: %s (%d bytes)", header.Filename, header.Size)
 		// 设置HTTP状态码为400 Bad Request
 		w.WriteHeader(http.StatusBadRequest)
 		// 返回错误信息给客户端
-		json.NewEncoder(w).Encode(map[string]string{"error": "上传的文件过大", "message": "上传的文件大小不能超过1GB"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传的文件过大", "message": fmt.Sprintf("上传的文件大小不能超过%d字节", maxObjectSize)})
 		return
 	}
 
@@ -74,6 +222,21 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	// 获取文件的内容类型
 	contentType := header.Header.Get("Content-Type")
 
+	// 根据当前用户和配置的加密模式构造服务端加密选项
+	user, err := h.currentUser(r)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "无法识别当前用户"})
+		return
+	}
+	sse, err := encryption.ForUpload(h.Config, user.ID, user.EncryptionKeyID, user.Username)
+	if err != nil {
+		log.Printf("Error building server-side encryption options for %s: %v", user.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "构造加密选项失败", "message": err.Error()})
+		return
+	}
+
 	// 记录开始上传到MinIO的时间，用于计算上传耗时
 	startTime := time.Now()
 	log.Printf("Starting MinIO upload for %s", objectName)
@@ -82,8 +245,11 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 	// 调用MinIO客户端的PutObject方法上传文件
 	// 参数：上下文、存储桶名称、对象名称、文件内容、文件大小、上传选项
-	info, err := h.MinioClient.PutObject(ctx, h.Config.Minio.BucketName, objectName, file, header.Size, minio.PutObjectOptions{
-		ContentType: contentType, // 设置内容类型
+	info, err := h.minioClient().PutObject(ctx, h.Config.Minio.BucketName, objectName, file, header.Size, minio.PutObjectOptions{
+		ContentType:          contentType, // 设置内容类型
+		ServerSideEncryption: sse,         // 按配置的加密模式加密存储
+		// 记录上传者，使internal/events包的桶事件索引处理器能将其写入files表的uploader字段
+		UserMetadata: map[string]string{"uploader": user.Username},
 	})
 	if err != nil {
 		// 记录上传失败的错误
@@ -114,7 +280,8 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListFiles 处理获取文件列表的请求
-// 从MinIO存储桶中获取所有文件的列表并返回给客户端
+// 从Postgres的文件索引（files表）中读取元数据并返回给客户端
+// 该索引由internal/events包中的桶事件处理器异步维护，避免每次请求都对MinIO发起ListObjects扫描
 // 参数:
 //   - w: HTTP响应写入器
 //   - r: HTTP请求
@@ -124,59 +291,24 @@ func (h *FileHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 
 	// 记录开始获取文件列表的时间，用于计算耗时
 	startTime := time.Now()
-	// 记录开始获取文件列表的日志
-	log.Printf("Starting to list files from bucket: %s", h.Config.Minio.BucketName)
-
-	// 创建一个空的上下文
-	ctx := context.Background()
-	// 调用MinIO客户端的ListObjects方法获取存储桶中的所有对象
-	// 返回一个对象信息通道，可以通过遍历该通道获取所有对象
-	// Recursive: true表示递归列出所有对象，包括子目录中的对象
-	objectsCh := h.MinioClient.ListObjects(ctx, h.Config.Minio.BucketName, minio.ListObjectsOptions{Recursive: true})
-
-	// 用于存储文件信息的切片
-	var files []map[string]interface{}
-	// 标记是否发生错误的标志
-	var errorOccurred bool
-
-	// 遍历对象通道，获取每个对象的信息
-	for object := range objectsCh {
-		// 检查对象获取过程中是否有错误
-		if object.Err != nil {
-			// 记录获取对象列表失败的错误
-			log.Printf("Error listing objects: %v", object.Err)
-			// 设置错误标志
-			errorOccurred = true
-			// 设置HTTP状态码为500 Internal Server Error
-			w.WriteHeader(http.StatusInternalServerError)
-			// 返回错误信息给客户端
-			json.NewEncoder(w).Encode(map[string]string{
-				"error":   "获取文件列表失败",
-				"message": object.Err.Error(),
-			})
-			return
-		}
+	log.Printf("Starting to list files from index")
 
-		// 将对象的信息添加到文件列表中
-		files = append(files, map[string]interface{}{
-			"name":         object.Key,          // 文件名/键
-			"size":         object.Size,         // 文件大小
-			"lastModified": object.LastModified, // 最后修改时间
+	files, err := h.FileStore.ListFiles()
+	if err != nil {
+		log.Printf("Error listing files from index: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "获取文件列表失败",
+			"message": err.Error(),
 		})
+		return
 	}
 
-	// 计算获取文件列表的耗时（从开始获取到获取完成的时间差）
 	listDuration := time.Since(startTime)
+	log.Printf("Successfully listed %d files in %v", len(files), listDuration)
 
-	// 如果没有发生错误，返回文件列表给客户端
-	if !errorOccurred {
-		// 记录获取文件列表成功的信息
-		log.Printf("Successfully listed %d files in %v", len(files), listDuration)
-		// 设置HTTP状态码为200 OK
-		w.WriteHeader(http.StatusOK)
-		// 将文件列表编码为JSON并返回给客户端
-		json.NewEncoder(w).Encode(files)
-	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(files)
 }
 
 // DownloadFile 处理文件下载请求
@@ -194,11 +326,47 @@ func (h *FileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 要求调用者已登录，但sse-c下解密密钥必须按对象的uploader（而非发起下载请求的用户）派生：
+	// 密钥是HKDF(userID, keyID)的结果，上传时用的是上传者自己的userID/keyID，任何其他已登录
+	// 用户下载同一对象时，若改用自己的userID/keyID派生，算出的密钥会与上传时不一致，被MinIO拒绝
+	user, err := h.currentUser(r)
+	if err != nil || user == nil {
+		http.Error(w, "无法识别当前用户", http.StatusUnauthorized)
+		return
+	}
+
+	encUserID, encKeyID := user.ID, user.EncryptionKeyID
+	if h.Config.Minio.Encryption.Mode == encryption.ModeSSEC {
+		file, err := h.FileStore.GetFileByName(filename)
+		if err != nil {
+			log.Printf("Error looking up file index for %s: %v", filename, err)
+			http.Error(w, "检索文件信息时出错", http.StatusInternalServerError)
+			return
+		}
+		if file == nil || file.Uploader == "" {
+			http.Error(w, "找不到该文件的上传者信息，无法解密", http.StatusConflict)
+			return
+		}
+		uploader, err := h.UserStore.GetUserByUsername(file.Uploader)
+		if err != nil || uploader == nil {
+			http.Error(w, "上传者信息缺失，无法解密", http.StatusConflict)
+			return
+		}
+		encUserID, encKeyID = uploader.ID, uploader.EncryptionKeyID
+	}
+
+	sse, err := encryption.ForDownload(h.Config, encUserID, encKeyID)
+	if err != nil {
+		log.Printf("Error building server-side encryption options for %s: %v", user.Username, err)
+		http.Error(w, "该文件的加密密钥材料缺失，拒绝提供", http.StatusConflict)
+		return
+	}
+
 	// 创建一个空的上下文
 	ctx := context.Background()
 	// 调用MinIO客户端的GetObject方法获取指定的对象
 	// 参数：上下文、存储桶名称、对象名称、获取选项
-	object, err := h.MinioClient.GetObject(ctx, h.Config.Minio.BucketName, filename, minio.GetObjectOptions{})
+	object, err := h.minioClient().GetObject(ctx, h.Config.Minio.BucketName, filename, minio.GetObjectOptions{ServerSideEncryption: sse})
 	if err != nil {
 		// 记录获取对象失败的错误
 		log.Printf("Error getting object from MinIO: %v", err)
@@ -257,7 +425,7 @@ func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 	// 调用MinIO客户端的RemoveObject方法删除指定的对象
 	// 参数：上下文、存储桶名称、对象名称、删除选项
-	err := h.MinioClient.RemoveObject(ctx, h.Config.Minio.BucketName, filename, minio.RemoveObjectOptions{})
+	err := h.minioClient().RemoveObject(ctx, h.Config.Minio.BucketName, filename, minio.RemoveObjectOptions{})
 	if err != nil {
 		// 记录删除对象失败的错误
 		log.Printf("Error deleting object from MinIO: %v", err)
@@ -274,3 +442,142 @@ func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 		"filename": filename, // 被删除的文件名
 	})
 }
+
+// 检索接口的分页参数默认值与上限
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
+// searchResult 是检索接口返回的单条结果，在文件元数据基础上附带一个短期有效的预签名下载地址
+type searchResult struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ETag        string    `json:"etag"`
+	ContentType string    `json:"contentType"`
+	Uploader    string    `json:"uploader"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"createdAt"`
+	DownloadURL string    `json:"downloadUrl"`
+}
+
+// searchResponse 是GET /api/search的响应体
+type searchResponse struct {
+	Results  []searchResult `json:"results"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+	Total    int            `json:"total"`
+}
+
+// Search 处理文件检索请求
+// 支持按上传者、内容类型、文件大小区间、标签过滤，以及基于Postgres tsvector的全文检索（匹配文件名/标签/正文）
+// 支持按相关度（relevance，需配合q使用）、大小（size）或修改时间（modified）排序，默认按文件名排序
+// 加密模式为none时，每条结果都附带一个预签名下载地址，客户端可直接使用该地址从MinIO下载，无需再次申请；
+// 其他加密模式下downloadUrl为空，需改用/api/download
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *FileHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(q.Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultSearchPageSize
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+
+	var minSize, maxSize int64
+	if v := q.Get("minSize"); v != "" {
+		minSize, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("maxSize"); v != "" {
+		maxSize, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	params := database.SearchParams{
+		Query:       q.Get("q"),
+		Uploader:    q.Get("uploader"),
+		ContentType: q.Get("contentType"),
+		Tag:         q.Get("tag"),
+		MinSize:     minSize,
+		MaxSize:     maxSize,
+		Sort:        q.Get("sort"),
+		Page:        page,
+		PageSize:    pageSize,
+	}
+
+	files, total, err := h.FileStore.SearchFiles(params)
+	if err != nil {
+		log.Printf("Error searching files: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "检索文件失败", "message": err.Error()})
+		return
+	}
+
+	expiry := h.Config.PresignExpiryDuration()
+	ctx := context.Background()
+	results := make([]searchResult, 0, len(files))
+	for _, f := range files {
+		downloadURL := ""
+		// 加密模式不为none时，预签名GET既无法像/api/download那样按uploader派生sse-c密钥，
+		// 对sse-c也无法携带解密所需的客户密钥请求头，生成的URL会在客户端直接访问时失败，
+		// 因此这里留空DownloadURL，提示客户端改用/api/download
+		if h.Config.Minio.Encryption.Mode == encryption.ModeNone {
+			presignedURL, err := h.minioClient().PresignedGetObject(ctx, h.Config.Minio.BucketName, f.Name, expiry, make(url.Values))
+			if err != nil {
+				log.Printf("Error generating presigned download URL for %s: %v", f.Name, err)
+			} else {
+				downloadURL = presignedURL.String()
+			}
+		}
+		results = append(results, searchResult{
+			Name: f.Name, Size: f.Size, ETag: f.ETag, ContentType: f.ContentType,
+			Uploader: f.Uploader, Tags: f.Tags, CreatedAt: f.CreatedAt, DownloadURL: downloadURL,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(searchResponse{Results: results, Page: page, PageSize: pageSize, Total: total})
+}
+
+// updateTagsRequest 是PATCH /api/files/{name}/tags的请求体
+type updateTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// UpdateTags 处理为指定文件更新标签的请求，新标签会立即参与后续的全文检索
+//   - w: HTTP响应写入器
+//   - r: HTTP请求（URL路径参数中携带name）
+func (h *FileHandler) UpdateTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := mux.Vars(r)["name"]
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "文件名不能为空"})
+		return
+	}
+
+	var req updateTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败", "message": err.Error()})
+		return
+	}
+
+	if err := h.FileStore.UpdateTags(name, req.Tags); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "更新标签失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "标签更新成功"})
+}
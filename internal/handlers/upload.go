@@ -0,0 +1,336 @@
+// package handlers 提供了处理HTTP请求的处理器
+package handlers
+
+import (
+	"context"       // 用于控制请求的上下文
+	"encoding/json" // 用于JSON编解码
+	"log"           // 用于日志记录
+	"net/http"      // 提供HTTP客户端和服务器实现
+	"path/filepath" // 用于处理文件路径
+	"sort"          // 用于按分片编号排序
+	"strconv"       // 用于解析路径中的分片编号
+
+	"github.com/gorilla/mux"       // 用于读取路径参数
+	"github.com/minio/minio-go/v7" // MinIO客户端
+
+	"github.com/sulibao/knowledge/internal/config"             // 配置管理
+	"github.com/sulibao/knowledge/internal/database"           // 数据库操作相关包
+	"github.com/sulibao/knowledge/internal/encryption"         // 服务端加密选项构造
+	"github.com/sulibao/knowledge/internal/middleware"
+	minio_client "github.com/sulibao/knowledge/internal/minio" // 持有可热重载的MinIO客户端
+	"github.com/sulibao/knowledge/internal/models"             // 数据模型
+)
+
+// UploadHandler 结构体用于处理分片（可续传）上传相关的HTTP请求
+type UploadHandler struct {
+	MinioPool   *minio_client.Pool    // MinIO客户端池，每次操作都通过Client()取用，使端点/凭证热重载对本处理器生效
+	Config      *config.Config        // 应用配置信息
+	UploadStore *database.UploadStore // 分片上传会话存储
+	UserStore   *database.UserStore   // 用户存储，用于按用户名换取用户ID
+}
+
+// NewUploadHandler 创建并返回一个新的UploadHandler实例
+func NewUploadHandler(minioPool *minio_client.Pool, cfg *config.Config, uploadStore *database.UploadStore, userStore *database.UserStore) *UploadHandler {
+	return &UploadHandler{MinioPool: minioPool, Config: cfg, UploadStore: uploadStore, UserStore: userStore}
+}
+
+// minioClient 返回当前生效的MinIO客户端，每次调用都从MinioPool取最新实例，而不是缓存一份
+func (h *UploadHandler) minioClient() *minio.Client {
+	return h.MinioPool.Client()
+}
+
+// currentUser 从请求上下文中解析出发起请求的用户记录
+func (h *UploadHandler) currentUser(r *http.Request) (*models.User, error) {
+	username, ok := middleware.UsernameFromContext(r.Context())
+	if !ok {
+		return nil, nil
+	}
+	return h.UserStore.GetUserByUsername(username)
+}
+
+// requireSessionOwner 校验当前请求的发起者是否为该上传会话的创建者
+// 上传会话不记录任何访问控制列表，任何能拿到uploadId的已登录用户原本都能对会话做任意操作，
+// 对另一用户发起的会话这样做不仅是越权访问，sse-c下还会用错误的身份派生分片密钥，
+// 导致拼装出的对象无法被任何人解密
+//   - w, r: 用于在校验失败时直接写出响应
+//   - session: 待校验归属的上传会话
+//   - *models.User: 校验通过时返回当前用户，供调用方复用（如派生其加密密钥）
+//   - bool: 是否通过校验，为false时已经写过响应，调用方应直接return
+func (h *UploadHandler) requireSessionOwner(w http.ResponseWriter, r *http.Request, session *models.UploadSession) (*models.User, bool) {
+	user, err := h.currentUser(r)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "无法识别当前用户"})
+		return nil, false
+	}
+	if session.UserID != user.ID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "无权操作该上传会话"})
+		return nil, false
+	}
+	return user, true
+}
+
+// initUploadRequest 是POST /api/uploads的请求体
+type initUploadRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+}
+
+// InitUpload 处理分片上传的初始化请求
+// 在MinIO侧发起一次S3分片上传，并在Postgres中创建对应的会话记录
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *UploadHandler) InitUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := h.currentUser(r)
+	if err != nil || user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "无法识别当前用户"})
+		return
+	}
+
+	// 并发上传数限制，避免单个用户占用过多进行中的分片上传
+	activeCount, err := h.UploadStore.CountActiveSessionsForUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "检查并发上传数量失败", "message": err.Error()})
+		return
+	}
+	if activeCount >= h.Config.MaxConcurrentUploadsPerUser() {
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{"error": "进行中的上传数量已达上限，请先完成或取消现有上传"})
+		return
+	}
+
+	var req initUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Filename == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "请求体解析失败或文件名为空"})
+		return
+	}
+
+	objectKey := filepath.Base(req.Filename)
+
+	// 根据当前用户和配置的加密模式构造服务端加密选项，分片上传的每一步都需要沿用同一份选项
+	sse, err := encryption.ForUpload(h.Config, user.ID, user.EncryptionKeyID, user.Username)
+	if err != nil {
+		log.Printf("Error building server-side encryption options for %s: %v", user.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "构造加密选项失败", "message": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	core := minio.Core{Client: h.minioClient()}
+	minioUploadID, err := core.NewMultipartUpload(ctx, h.Config.Minio.BucketName, objectKey, minio.PutObjectOptions{
+		ContentType:          req.ContentType,
+		ServerSideEncryption: sse,
+		// 记录上传者，使internal/events包的桶事件索引处理器能将其写入files表的uploader字段
+		UserMetadata: map[string]string{"uploader": user.Username},
+	})
+	if err != nil {
+		log.Printf("Error initiating multipart upload for %s: %v", objectKey, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "初始化分片上传失败", "message": err.Error()})
+		return
+	}
+
+	session, err := h.UploadStore.CreateSession(user.ID, objectKey, minioUploadID)
+	if err != nil {
+		log.Printf("Error creating upload session for %s: %v", objectKey, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "保存上传会话失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// UploadPart 处理单个分片的上传请求
+// 客户端在init之后，对每个分片发起一次PUT请求，body即为该分片的原始字节
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *UploadHandler) UploadPart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	uploadID := vars["uploadId"]
+	partNumber, err := strconv.Atoi(vars["partNumber"])
+	if err != nil || partNumber < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "分片编号无效"})
+		return
+	}
+
+	session, err := h.UploadStore.GetSession(uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "查询上传会话失败", "message": err.Error()})
+		return
+	}
+	if session == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传会话不存在"})
+		return
+	}
+
+	user, ok := h.requireSessionOwner(w, r, session)
+	if !ok {
+		return
+	}
+	// sse-c模式下，每个分片都必须携带初始化分片上传时所使用的同一把客户密钥
+	sse, err := encryption.ForUpload(h.Config, user.ID, user.EncryptionKeyID, user.Username)
+	if err != nil {
+		log.Printf("Error building server-side encryption options for %s: %v", user.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "构造加密选项失败", "message": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	core := minio.Core{Client: h.minioClient()}
+	objectPart, err := core.PutObjectPart(
+		ctx, h.Config.Minio.BucketName, session.ObjectKey, session.MinioUploadID, partNumber,
+		r.Body, r.ContentLength, minio.PutObjectPartOptions{ServerSideEncryption: sse},
+	)
+	if err != nil {
+		log.Printf("Error uploading part %d for upload %s: %v", partNumber, uploadID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传分片失败", "message": err.Error()})
+		return
+	}
+
+	part := models.UploadPart{PartNumber: partNumber, ETag: objectPart.ETag, Size: objectPart.Size}
+	if err := h.UploadStore.AddPart(uploadID, part); err != nil {
+		log.Printf("Error recording part %d for upload %s: %v", partNumber, uploadID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "记录已上传分片失败", "message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(part)
+}
+
+// GetUpload 返回指定上传会话的当前状态，供客户端断线重连后判断哪些分片已上传
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uploadID := mux.Vars(r)["uploadId"]
+	session, err := h.UploadStore.GetSession(uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "查询上传会话失败", "message": err.Error()})
+		return
+	}
+	if session == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传会话不存在"})
+		return
+	}
+	if _, ok := h.requireSessionOwner(w, r, session); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(session)
+}
+
+// CompleteUpload 处理分片上传的完成请求
+// 将所有已记录的分片按编号排序后提交给MinIO完成整个对象的拼装
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *UploadHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uploadID := mux.Vars(r)["uploadId"]
+	session, err := h.UploadStore.GetSession(uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "查询上传会话失败", "message": err.Error()})
+		return
+	}
+	if session == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传会话不存在"})
+		return
+	}
+	if _, ok := h.requireSessionOwner(w, r, session); !ok {
+		return
+	}
+
+	sortedParts := make([]models.UploadPart, len(session.Parts))
+	copy(sortedParts, session.Parts)
+	sort.Slice(sortedParts, func(i, j int) bool { return sortedParts[i].PartNumber < sortedParts[j].PartNumber })
+
+	completeParts := make([]minio.CompletePart, len(sortedParts))
+	for i, p := range sortedParts {
+		completeParts[i] = minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+
+	ctx := context.Background()
+	core := minio.Core{Client: h.minioClient()}
+	info, err := core.CompleteMultipartUpload(ctx, h.Config.Minio.BucketName, session.ObjectKey, session.MinioUploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		log.Printf("Error completing upload %s: %v", uploadID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "完成分片上传失败", "message": err.Error()})
+		return
+	}
+
+	if err := h.UploadStore.DeleteSession(uploadID); err != nil {
+		log.Printf("Error cleaning up upload session %s: %v", uploadID, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "文件上传成功",
+		"filename": session.ObjectKey,
+		"size":     info.Size,
+	})
+}
+
+// AbortUpload 处理分片上传的中止请求
+// 通知MinIO释放已上传的分片并清理本地的会话记录
+//   - w: HTTP响应写入器
+//   - r: HTTP请求
+func (h *UploadHandler) AbortUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uploadID := mux.Vars(r)["uploadId"]
+	session, err := h.UploadStore.GetSession(uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "查询上传会话失败", "message": err.Error()})
+		return
+	}
+	if session == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "上传会话不存在"})
+		return
+	}
+	if _, ok := h.requireSessionOwner(w, r, session); !ok {
+		return
+	}
+
+	ctx := context.Background()
+	core := minio.Core{Client: h.minioClient()}
+	if err := core.AbortMultipartUpload(ctx, h.Config.Minio.BucketName, session.ObjectKey, session.MinioUploadID); err != nil {
+		log.Printf("Error aborting upload %s: %v", uploadID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "中止分片上传失败", "message": err.Error()})
+		return
+	}
+
+	if err := h.UploadStore.DeleteSession(uploadID); err != nil {
+		log.Printf("Error cleaning up upload session %s: %v", uploadID, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "上传已中止"})
+}
@@ -4,42 +4,58 @@ package middleware
 import (
 	"net/http" // 提供HTTP客户端和服务器实现
 
-	"github.com/gorilla/sessions" // 提供cookie和文件系统会话存储
+	"github.com/sulibao/knowledge/internal/database" // 用于校验访问令牌是否已被吊销
 )
 
 var (
-	key = []byte("super-secret-key") // 用于加密和验证会话cookie的密钥
-	// Store 是全局会话存储，用于管理用户会话
-	Store *sessions.CookieStore
+	// authKeys 是签发和校验访问令牌使用的密钥材料（HS256对称密钥或RS256密钥对），由InitAuth在启动时注入
+	authKeys KeySet
+	// userStore 用于吊销状态查询（按jti关联的刷新令牌记录）
+	userStore *database.UserStore
 )
 
-// init 初始化会话存储并配置cookie选项
-// 在包被导入时自动执行
-func init() {
-	// 使用密钥创建新的cookie存储
-	Store = sessions.NewCookieStore(key)
-	// 配置cookie选项
-	Store.Options.HttpOnly = true                     // 防止JavaScript访问cookie，增强安全性
-	Store.Options.Secure = false                      // 是否仅通过HTTPS发送cookie，生产环境中应设为true
-	Store.Options.SameSite = http.SameSiteDefaultMode // 控制第三方网站请求时cookie的发送策略，可根据生产需求调整
+// accessTokenCookieName 是HTML页面场景下存放访问令牌的cookie名称
+// API调用应优先使用Authorization: Bearer头，该cookie仅作为浏览器直接跳转页面时的兜底方案
+const accessTokenCookieName = "access_token"
+
+// InitAuth 注入JWT密钥材料和用户存储，供AuthRequired校验令牌和吊销状态使用
+//   - keys: 签发访问令牌时使用的密钥材料及算法，见LoadKeySet
+//   - store: 用户存储，用于按jti查询刷新令牌是否已被吊销
+func InitAuth(keys KeySet, store *database.UserStore) {
+	authKeys = keys
+	userStore = store
 }
 
-// AuthRequired 是一个中间件函数，用于验证用户是否已认证
-// 如果用户未认证，将重定向到登录页面
+// AuthRequired 是一个中间件函数，用于验证请求携带的JWT访问令牌
+// 优先从Authorization: Bearer头解析令牌，找不到时回退读取access_token cookie（用于HTML页面）
+// 验证通过后，将用户名、角色和jti写入请求上下文，供后续处理器和AuthorizeRequired使用
 //   - next: 下一个要执行的HTTP处理器
 //   - http.Handler: 包装后的HTTP处理器
 func AuthRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 从请求中获取会话
-		session, _ := Store.Get(r, "session-name")
+		tokenString := ExtractBearerToken(r.Header.Get("Authorization"))
+		if tokenString == "" {
+			if cookie, err := r.Cookie(accessTokenCookieName); err == nil {
+				tokenString = cookie.Value
+			}
+		}
+		if tokenString == "" {
+			http.Error(w, "未提供认证凭据", http.StatusUnauthorized)
+			return
+		}
 
-		// 检查用户是否已认证
-		if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
-			// 未认证，重定向到登录页面
-			http.Redirect(w, r, "/login", http.StatusFound)
+		claims, err := ParseAccessToken(tokenString, authKeys)
+		if err != nil {
+			http.Error(w, "认证凭据无效或已过期", http.StatusUnauthorized)
 			return
 		}
-		// 用户已认证，继续处理请求
-		next.ServeHTTP(w, r)
+
+		if revoked, err := userStore.IsRevoked(claims.ID); err == nil && revoked {
+			http.Error(w, "认证凭据已被吊销", http.StatusUnauthorized)
+			return
+		}
+
+		// 将认证信息写入请求上下文，继续处理请求
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
 	})
 }
@@ -0,0 +1,223 @@
+// package middleware 包含HTTP请求处理的中间件组件
+package middleware
+
+import (
+	"database/sql" // 提供SQL数据库接口
+	"fmt"          // 格式化输出
+	"net/http"     // 提供HTTP客户端和服务器实现
+	"strings"      // 字符串操作函数
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// Enforcer 是全局的Casbin鉴权器，策略数据来源于Postgres中的casbin_rule表
+var Enforcer *casbin.Enforcer
+
+// rbacModelText 定义了本系统使用的鉴权模型：基于角色、资源路径和HTTP方法
+// obj支持keyMatch通配符（如"/api/*"），act为"*"时匹配任意方法
+const rbacModelText = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+// InitCasbin 基于内置的RBAC模型和Postgres策略表初始化全局Enforcer
+//   - db: 数据库连接对象，策略通过sqlAdapter从casbin_rule表加载
+//   - error: 如果初始化过程中发生错误，返回相应的错误信息
+func InitCasbin(db *sql.DB) error {
+	m, err := model.NewModelFromString(rbacModelText)
+	if err != nil {
+		return fmt.Errorf("解析casbin模型时出错: %w", err)
+	}
+
+	enforcer, err := casbin.NewEnforcer(m, newSQLAdapter(db))
+	if err != nil {
+		return fmt.Errorf("创建casbin enforcer时出错: %w", err)
+	}
+
+	if err := enforcer.LoadPolicy(); err != nil {
+		return fmt.Errorf("加载casbin策略时出错: %w", err)
+	}
+
+	Enforcer = enforcer
+	return nil
+}
+
+// AuthorizeRequired 是一个中间件工厂函数，要求请求携带的角色对(obj, act)拥有casbin授权
+// 需要配合AuthRequired先行解析出角色并写入请求上下文
+//   - obj: 受保护的资源标识，通常是请求路径，如"/api/upload"
+//   - act: 操作方式，通常是HTTP方法，如"POST"
+//   - func(http.Handler) http.Handler: 包装后的中间件
+func AuthorizeRequired(obj, act string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := RoleFromContext(r.Context())
+			if !ok {
+				http.Error(w, "未认证", http.StatusUnauthorized)
+				return
+			}
+
+			allowed, err := Enforcer.Enforce(role, obj, act)
+			if err != nil {
+				http.Error(w, "权限校验失败", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "没有权限访问该资源", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthorizeRequestPath 是AuthorizeRequired的变体，使用请求的实际URL路径作为资源标识
+// 适用于携带路径参数的动态路由（如/api/uploads/{uploadId}/parts/{partNumber}），
+// 这类路由无法在注册时提前确定一个固定的obj字符串
+//   - act: 操作方式，通常是HTTP方法，如"PUT"
+//   - func(http.Handler) http.Handler: 包装后的中间件
+func AuthorizeRequestPath(act string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := RoleFromContext(r.Context())
+			if !ok {
+				http.Error(w, "未认证", http.StatusUnauthorized)
+				return
+			}
+
+			allowed, err := Enforcer.Enforce(role, r.URL.Path, act)
+			if err != nil {
+				http.Error(w, "权限校验失败", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "没有权限访问该资源", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sqlAdapter 是一个基于database/sql的最小化casbin persist.Adapter实现
+// 复用应用已有的PostgreSQL连接，避免引入额外的ORM依赖
+type sqlAdapter struct {
+	db *sql.DB
+}
+
+// newSQLAdapter 创建并返回一个新的sqlAdapter实例
+func newSQLAdapter(db *sql.DB) *sqlAdapter {
+	return &sqlAdapter{db: db}
+}
+
+// LoadPolicy 从casbin_rule表加载全部策略行到model中
+func (a *sqlAdapter) LoadPolicy(m model.Model) error {
+	rows, err := a.db.Query("SELECT ptype, v0, v1, v2, v3, v4, v5 FROM casbin_rule")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fields [7]string
+		if err := rows.Scan(&fields[0], &fields[1], &fields[2], &fields[3], &fields[4], &fields[5], &fields[6]); err != nil {
+			return err
+		}
+		persist.LoadPolicyLine(toPolicyLine(fields[:]), m)
+	}
+	return rows.Err()
+}
+
+// SavePolicy 将model中的全部策略覆盖写入casbin_rule表
+func (a *sqlAdapter) SavePolicy(m model.Model) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM casbin_rule"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for ptype, assertion := range m["p"] {
+		for _, rule := range assertion.Policy {
+			if err := a.insertRule(tx, ptype, rule); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	for ptype, assertion := range m["g"] {
+		for _, rule := range assertion.Policy {
+			if err := a.insertRule(tx, ptype, rule); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// AddPolicy 向casbin_rule表追加一条策略
+func (a *sqlAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.insertRule(a.db, ptype, rule)
+}
+
+// RemovePolicy 从casbin_rule表删除一条精确匹配的策略
+func (a *sqlAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	query := "DELETE FROM casbin_rule WHERE ptype = $1"
+	args := []interface{}{ptype}
+	for i, v := range rule {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND v%d = $%d", i, len(args))
+	}
+	_, err := a.db.Exec(query, args...)
+	return err
+}
+
+// RemoveFilteredPolicy 按字段索引过滤删除策略（此处仅支持casbin运行时偶尔用到的精简场景）
+func (a *sqlAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	query := "DELETE FROM casbin_rule WHERE ptype = $1"
+	args := []interface{}{ptype}
+	for i, v := range fieldValues {
+		if v == "" {
+			continue
+		}
+		args = append(args, v)
+		query += fmt.Sprintf(" AND v%d = $%d", fieldIndex+i, len(args))
+	}
+	_, err := a.db.Exec(query, args...)
+	return err
+}
+
+// insertRule 执行具体的策略行插入
+func (a *sqlAdapter) insertRule(execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, ptype string, rule []string) error {
+	var v [6]string
+	copy(v[:], rule)
+	_, err := execer.Exec(
+		"INSERT INTO casbin_rule (ptype, v0, v1, v2, v3, v4, v5) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		ptype, v[0], v[1], v[2], v[3], v[4], v[5],
+	)
+	return err
+}
+
+// toPolicyLine 将casbin_rule的一行数据拼接为LoadPolicyLine期望的逗号分隔格式
+// 末尾的空字段会被裁掉，避免污染策略参数数量
+func toPolicyLine(fields []string) string {
+	end := len(fields)
+	for end > 1 && fields[end-1] == "" {
+		end--
+	}
+	return strings.Join(fields[:end], ", ")
+}
@@ -0,0 +1,216 @@
+// package middleware 包含HTTP请求处理的中间件组件
+package middleware
+
+import (
+	"context"       // 用于在请求处理链路中传递认证信息
+	"crypto/rsa"    // RS256签名使用的RSA密钥类型
+	"crypto/x509"   // 解析PEM编码的RSA密钥
+	"encoding/pem"  // 解析PEM编码的密钥文件
+	"errors"        // 用于构造哨兵错误
+	"fmt"           // 格式化输出
+	"os"            // 读取RS256密钥文件
+	"strings"       // 字符串操作函数
+	"time"          // 时间相关操作
+
+	"github.com/golang-jwt/jwt/v5" // JWT签发与校验
+)
+
+// contextKey 是一个私有类型，避免与其他包写入context.Context的键发生冲突
+type contextKey string
+
+const (
+	usernameContextKey contextKey = "username"
+	roleContextKey     contextKey = "role"
+	jtiContextKey      contextKey = "jti"
+)
+
+// ErrTokenRevoked 表示令牌对应的jti已被吊销
+var ErrTokenRevoked = errors.New("令牌已被吊销")
+
+// Claims 是访问令牌携带的自定义声明
+type Claims struct {
+	Username string `json:"username"` // 登录用户名，即鉴权主体
+	Role     string `json:"role"`     // 用户角色，供casbin enforcer使用
+	jwt.RegisteredClaims
+}
+
+// KeySet 描述签发/校验访问令牌所需的密钥材料及签名算法
+// Algorithm为空或"HS256"时使用HMACSecret对称签名（沿用本系统最初的签发方式）；
+// 为"RS256"时使用RSAPrivateKey签发、RSAPublicKey校验，适配多服务共享校验公钥、
+// 签发私钥仅由本服务持有的部署场景
+type KeySet struct {
+	Algorithm     string          // "HS256"（默认）或"RS256"
+	HMACSecret    []byte          // HS256下签发/校验共用的对称密钥
+	RSAPrivateKey *rsa.PrivateKey // RS256下签发访问令牌使用
+	RSAPublicKey  *rsa.PublicKey  // RS256下校验访问令牌使用
+	Issuer        string          // 签发者标识，写入iss声明；留空时不设置该声明
+}
+
+// LoadKeySet 根据算法名及对应的密钥材料构造KeySet
+//   - algorithm: "HS256"或"RS256"，留空时按"HS256"处理
+//   - secret: HS256下使用的对称密钥，RS256下忽略
+//   - privateKeyPath/publicKeyPath: RS256下分别存放PEM编码RSA私钥/公钥的文件路径，HS256下忽略
+//   - issuer: 写入令牌iss声明的签发者标识，留空时不设置
+//   - KeySet: 构造好的密钥材料
+//   - error: RS256下密钥文件读取或解析失败时返回相应的错误信息
+func LoadKeySet(algorithm, secret, privateKeyPath, publicKeyPath, issuer string) (KeySet, error) {
+	if algorithm == "" {
+		algorithm = "HS256"
+	}
+
+	if algorithm != "RS256" {
+		return KeySet{Algorithm: algorithm, HMACSecret: []byte(secret), Issuer: issuer}, nil
+	}
+
+	privKey, err := loadRSAPrivateKey(privateKeyPath)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("加载RS256私钥时出错: %w", err)
+	}
+	pubKey, err := loadRSAPublicKey(publicKeyPath)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("加载RS256公钥时出错: %w", err)
+	}
+	return KeySet{Algorithm: algorithm, RSAPrivateKey: privKey, RSAPublicKey: pubKey, Issuer: issuer}, nil
+}
+
+// loadRSAPrivateKey 从PEM文件中解析PKCS1或PKCS8编码的RSA私钥
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("未能解析PEM格式私钥: %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是RSA私钥", path)
+	}
+	return rsaKey, nil
+}
+
+// loadRSAPublicKey 从PEM文件中解析PKIX编码的RSA公钥
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("未能解析PEM格式公钥: %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是RSA公钥", path)
+	}
+	return rsaKey, nil
+}
+
+// GenerateAccessToken 按keys指定的算法签发一个携带用户名和角色的访问令牌
+//   - username: 令牌主体对应的用户名
+//   - role: 用户角色
+//   - keys: 签名使用的密钥材料及算法，见LoadKeySet
+//   - ttl: 令牌有效期
+//   - jti: 令牌唯一标识，用于吊销
+//   - string: 签发成功后的JWT字符串
+//   - error: 如果签发过程中发生错误，返回相应的错误信息
+func GenerateAccessToken(username, role string, keys KeySet, ttl time.Duration, jti string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        jti,
+			Issuer:    keys.Issuer,
+		},
+	}
+
+	if keys.Algorithm == "RS256" {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		return token.SignedString(keys.RSAPrivateKey)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(keys.HMACSecret)
+}
+
+// ParseAccessToken 按keys指定的算法校验并解析访问令牌，返回其中的自定义声明
+//   - tokenString: 待解析的JWT字符串
+//   - keys: 签发时使用的密钥材料及算法，见LoadKeySet
+//   - *Claims: 解析成功后的声明
+//   - error: 令牌无效、过期或签名不匹配时返回错误
+func ParseAccessToken(tokenString string, keys KeySet) (*Claims, error) {
+	// 显式限定可接受的签名算法，否则keyfunc只看keys.Algorithm却不比对t.Method：
+	// RS256部署下，攻击者可伪造一个alg=HS256、用已公开的RSA公钥当作HMAC密钥签名的令牌，
+	// 若keyfunc仍返回该公钥，会被jwt库当作合法的HS256签名放行（经典的alg混淆攻击）
+	validMethod := "HS256"
+	if keys.Algorithm == "RS256" {
+		validMethod = "RS256"
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if keys.Algorithm == "RS256" {
+			return keys.RSAPublicKey, nil
+		}
+		return keys.HMACSecret, nil
+	}, jwt.WithValidMethods([]string{validMethod}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("令牌无效")
+	}
+	return claims, nil
+}
+
+// ExtractBearerToken 从Authorization请求头中提取Bearer令牌
+//   - header: Authorization请求头的原始值
+//   - string: 提取出的令牌字符串，未找到时为空
+func ExtractBearerToken(header string) string {
+	const prefix = "Bearer "
+	if strings.HasPrefix(header, prefix) {
+		return strings.TrimPrefix(header, prefix)
+	}
+	return ""
+}
+
+// WithClaims 将校验通过的声明写入请求上下文，供后续处理器和AuthorizeRequired读取
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, usernameContextKey, claims.Username)
+	ctx = context.WithValue(ctx, roleContextKey, claims.Role)
+	ctx = context.WithValue(ctx, jtiContextKey, claims.ID)
+	return ctx
+}
+
+// UsernameFromContext 从请求上下文中读取已认证的用户名
+func UsernameFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(usernameContextKey).(string)
+	return v, ok
+}
+
+// RoleFromContext 从请求上下文中读取已认证用户的角色
+func RoleFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(roleContextKey).(string)
+	return v, ok
+}
+
+// JTIFromContext 从请求上下文中读取访问令牌的jti
+func JTIFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(jtiContextKey).(string)
+	return v, ok
+}
@@ -0,0 +1,55 @@
+// package minio_client 提供MinIO对象存储服务的客户端功能
+package minio_client
+
+import (
+	"log"  // 提供日志记录
+	"sync" // 用于保护客户端指针的并发读写
+
+	"github.com/minio/minio-go/v7" // MinIO Go客户端
+
+	"github.com/sulibao/knowledge/internal/config" // 导入配置包
+)
+
+// Pool 持有一个可被整体替换的*minio.Client，用于配合config.Manager.OnMinioChange实现
+// MinIO端点/凭证变更时的热重载。internal/handlers、internal/events中的各处理器都持有*Pool
+// 本身而非直接持有*minio.Client，并在每次操作时经Client()取用，因此Reload替换的新客户端
+// 能被这些调用方感知到
+//
+// 已知局限：events.Listener订阅的桶通知是一条长连接，Reload不会主动打断它，
+// 新客户端只在该连接断开后的下一次重连时才会被取用，见events.Listener.listenLoop的文档
+type Pool struct {
+	mu     sync.RWMutex
+	client *minio.Client
+}
+
+// NewPool 基于已建立的MinIO客户端创建一个Pool
+//   - client: 已经建立好的MinIO客户端
+//   - *Pool: 新创建的Pool实例
+func NewPool(client *minio.Client) *Pool {
+	return &Pool{client: client}
+}
+
+// Client 返回当前持有的MinIO客户端
+func (p *Pool) Client() *minio.Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.client
+}
+
+// Reload 以新的MinIO配置建立客户端并整体替换当前客户端，符合config.MinioChangeFunc的签名，
+// 可直接传给config.Manager.OnMinioChange注册。新客户端建立失败时保留旧客户端不变
+//   - old: 变更前的MinIO配置（未使用，仅用于匹配订阅者签名）
+//   - newCfg: 变更后的MinIO配置
+func (p *Pool) Reload(old, newCfg config.MinioConfig) {
+	newClient, err := InitMinio(&config.Config{Minio: newCfg})
+	if err != nil {
+		log.Printf("Error establishing MinIO connection with reloaded configuration, keeping previous connection in effect: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	p.client = newClient
+	p.mu.Unlock()
+
+	log.Println("MinIO client reloaded successfully.")
+}
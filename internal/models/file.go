@@ -0,0 +1,18 @@
+// package models 包含应用程序使用的数据模型定义
+package models
+
+import "time"
+
+// File 对应Postgres files表中的一条对象元数据索引记录
+// 由internal/events包中的事件处理器在对象创建/删除时维护，ListFiles等读路径据此响应，无需直接扫描MinIO
+type File struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`        // 对象名称，即MinIO中的对象键
+	Size        int64     `json:"size"`        // 文件大小（字节）
+	ETag        string    `json:"etag"`        // MinIO返回的ETag
+	ContentType string    `json:"contentType"` // 内容类型
+	Uploader    string    `json:"uploader"`    // 上传者用户名，来自对象的用户自定义元数据
+	SHA256      string    `json:"sha256"`      // 文件内容的sha256摘要，用于去重
+	Tags        []string  `json:"tags"`        // 用户自定义标签
+	CreatedAt   time.Time `json:"createdAt"`   // 索引写入时间
+}
@@ -0,0 +1,23 @@
+// package models 包含应用程序使用的数据模型定义
+package models
+
+import "time"
+
+// UploadPart 记录一个已成功上传的分片信息
+// 断点续传时，客户端可据此判断哪些分片已存在，无需重新上传
+type UploadPart struct {
+	PartNumber int    `json:"partNumber"` // 分片编号，从1开始
+	ETag       string `json:"etag"`       // MinIO为该分片返回的ETag，拼装时需要
+	Size       int64  `json:"size"`       // 分片大小（字节）
+}
+
+// UploadSession 对应一次进行中的分片上传会话
+// 持久化在Postgres中，使上传可以在客户端断连后恢复
+type UploadSession struct {
+	ID            string       `json:"id"`           // 上传会话ID，返回给客户端作为uploadId
+	ObjectKey     string       `json:"objectKey"`     // 最终存储在MinIO中的对象名称
+	UserID        int          `json:"userId"`        // 发起上传的用户ID
+	MinioUploadID string       `json:"minioUploadId"` // MinIO（S3协议）分片上传ID
+	Parts         []UploadPart `json:"parts"`         // 已上传的分片列表
+	CreatedAt     time.Time    `json:"createdAt"`     // 会话创建时间
+}
@@ -1,10 +1,50 @@
 // package models 包含应用程序使用的数据模型定义
 package models
 
+import "time" // 用于用户的创建/更新时间字段
+
 // User 结构体定义了系统中用户的数据模型
 // 用于用户注册、登录和身份验证
 type User struct {
-	ID       int    `json:"id"`       // 用户唯一标识符，数据库主键
-	Username string `json:"username"` // 用户名，用于登录和显示
-	Password string `json:"password"` // 密码，存储为bcrypt哈希值，用于身份验证
+	ID              int       `json:"id"`        // 用户唯一标识符，数据库主键
+	Username        string    `json:"username"`  // 用户名，用于登录和显示
+	Password        string    `json:"password"`  // 密码，存储为密码哈希值，用于身份验证
+	Role            string    `json:"role"`       // 角色，用于Casbin按路径鉴权，取值为admin/uploader/viewer
+	Email           string    `json:"email"`      // 邮箱地址，用于找回密码等场景，允许为空
+	Permission      int       `json:"permission"` // 权限位掩码，在Role对应的路径策略之外，供更细粒度的能力位判断使用
+	IsActive        bool      `json:"isActive"`   // 账号是否启用，被禁用的用户无法登录
+	CreatedAt       time.Time `json:"createdAt"`  // 账号创建时间
+	UpdatedAt       time.Time `json:"updatedAt"`  // 账号信息最近一次更新时间
+	EncryptionKeyID string    `json:"-"`          // 加密密钥版本号，用于派生该用户的SSE-C密钥，不对外暴露
+}
+
+// 系统内置的角色常量，与casbin_rule表中预置的策略一一对应
+const (
+	RoleAdmin    = "admin"
+	RoleUploader = "uploader"
+	RoleViewer   = "viewer"
+)
+
+// 权限位掩码常量，按位组合，描述角色默认具备的能力集合
+// 这是对Role驱动的casbin路径策略的补充，而非替代：casbin仍然是HTTP接口的访问控制依据，
+// Permission用于在同一路径内做更细粒度的能力判断，目前由auth.Service.RequireAuth在管理员接口上要求PermManageUsers位
+const (
+	PermRead = 1 << iota
+	PermWrite
+	PermDelete
+	PermManageUsers
+)
+
+// DefaultPermission 返回指定角色的默认权限位掩码，创建用户时据此初始化Permission字段
+//   - role: 角色，取值为admin/uploader/viewer
+//   - int: 对应的权限位掩码
+func DefaultPermission(role string) int {
+	switch role {
+	case RoleAdmin:
+		return PermRead | PermWrite | PermDelete | PermManageUsers
+	case RoleUploader:
+		return PermRead | PermWrite | PermDelete
+	default:
+		return PermRead
+	}
 }
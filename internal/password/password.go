@@ -0,0 +1,183 @@
+// package password 提供可插拔的密码哈希与校验实现，使系统能够在bcrypt与Argon2id之间
+// 透明迁移：历史哈希继续以其原算法校验，校验通过后再按当前策略重新哈希并写回
+package password
+
+import (
+	"crypto/rand"     // 用于生成Argon2id的随机盐值
+	"crypto/subtle"   // 用于常数时间比较Argon2id哈希值，避免时序攻击
+	"encoding/base64" // 用于PHC格式中盐值/哈希值的编码
+	"fmt"             // 格式化输出与PHC格式的编解码
+	"strings"         // 用于按前缀判断编码哈希所属的算法
+
+	"golang.org/x/crypto/argon2" // Argon2id密钥派生
+	"golang.org/x/crypto/bcrypt" // bcrypt密码哈希
+)
+
+// Hasher 对密码进行哈希与校验
+// Verify返回的needsRehash用于提示调用方：该编码哈希仍然有效，但不符合当前哈希策略
+// （例如bcrypt cost低于当前策略，或Argon2id参数与当前策略不一致；跨算法的迁移见Identity），登录成功后应以当前算法重新哈希并写回
+type Hasher interface {
+	Hash(pw string) (string, error)
+	Verify(pw, encoded string) (ok bool, needsRehash bool, err error)
+	// Identity 返回该实现对应的算法标识（"bcrypt"或"argon2id"），用于让调用方判断一个哈希的
+	// 实际算法是否已经落后于当前配置的目标算法——Verify只能感知同算法内的参数漂移（如bcrypt cost
+	// 降低），无法感知"目标算法已经从bcrypt切换为argon2id"这种跨算法迁移，必须由调用方借助Identity
+	// 自行比较
+	Identity() string
+}
+
+// ForEncoded 根据已编码哈希的前缀选择对应的校验实现
+// 不认识的前缀返回nil，表示没有能够校验该编码哈希的实现
+//   - encoded: 存储在password列中的编码哈希
+//   - Hasher: 能够校验该编码哈希的实现，未知前缀时返回nil
+func ForEncoded(encoded string) Hasher {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return NewArgon2idHasher(DefaultArgon2idParams)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return NewDefaultBcryptHasher()
+	default:
+		return nil
+	}
+}
+
+// ---- bcrypt ----
+
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 创建一个按给定cost哈希/校验密码的bcrypt实现
+func NewBcryptHasher(cost int) Hasher {
+	return &bcryptHasher{cost: cost}
+}
+
+// NewDefaultBcryptHasher 创建一个使用bcrypt.DefaultCost的bcrypt实现
+func NewDefaultBcryptHasher() Hasher {
+	return NewBcryptHasher(bcrypt.DefaultCost)
+}
+
+func (h *bcryptHasher) Hash(pw string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("生成bcrypt哈希时出错: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Verify(pw, encoded string) (bool, bool, error) {
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(pw)); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("校验bcrypt哈希时出错: %w", err)
+	}
+
+	// 密码匹配，但若该哈希的cost低于当前策略，提示调用方重新哈希
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true, false, nil
+	}
+	return true, cost < h.cost, nil
+}
+
+// Identity 返回"bcrypt"
+func (h *bcryptHasher) Identity() string {
+	return "bcrypt"
+}
+
+// ---- argon2id ----
+
+// Argon2idParams 描述Argon2id的哈希参数
+type Argon2idParams struct {
+	Time    uint32 // 时间成本（迭代次数）
+	Memory  uint32 // 内存成本，单位KiB
+	Threads uint8  // 并行度
+	SaltLen uint32 // 盐值长度（字节）
+	KeyLen  uint32 // 派生密钥长度（字节）
+}
+
+// DefaultArgon2idParams 是推荐的Argon2id参数：时间成本2、内存64MiB、4线程、16字节盐、32字节密钥
+var DefaultArgon2idParams = Argon2idParams{Time: 2, Memory: 64 * 1024, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher 创建一个按给定参数哈希/校验密码的Argon2id实现
+func NewArgon2idHasher(params Argon2idParams) Hasher {
+	return &argon2idHasher{params: params}
+}
+
+func (h *argon2idHasher) Hash(pw string) (string, error) {
+	salt := make([]byte, h.params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成Argon2id盐值时出错: %w", err)
+	}
+	hash := argon2.IDKey([]byte(pw), salt, h.params.Time, h.params.Memory, h.params.Threads, h.params.KeyLen)
+	return encodeArgon2id(h.params, salt, hash), nil
+}
+
+func (h *argon2idHasher) Verify(pw, encoded string) (bool, bool, error) {
+	params, salt, hash, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	computed := argon2.IDKey([]byte(pw), salt, params.Time, params.Memory, params.Threads, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(hash, computed) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := params.Time != h.params.Time || params.Memory != h.params.Memory || params.Threads != h.params.Threads
+	return true, needsRehash, nil
+}
+
+// Identity 返回"argon2id"
+func (h *argon2idHasher) Identity() string {
+	return "argon2id"
+}
+
+// encodeArgon2id 按PHC字符串格式编码Argon2id参数、盐值与哈希值
+// 格式: $argon2id$v=19$m=65536,t=2,p=4$<base64盐值>$<base64哈希值>
+func encodeArgon2id(p Argon2idParams, salt, hash []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+}
+
+// decodeArgon2id 解析PHC格式的Argon2id编码哈希，返回其中记录的参数、盐值与哈希值
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("不是合法的argon2id编码哈希")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("解析argon2id版本号时出错: %w", err)
+	}
+	if version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("不支持的argon2id版本号: %d", version)
+	}
+
+	var memory, t uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &t, &threads); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("解析argon2id参数时出错: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("解析argon2id盐值时出错: %w", err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("解析argon2id哈希值时出错: %w", err)
+	}
+
+	return Argon2idParams{Time: t, Memory: memory, Threads: threads}, salt, hash, nil
+}
@@ -10,13 +10,17 @@ package main
 
 // 导入所需的包
 import (
+	"context"  // 用于控制桶事件监听与协调扫描的生命周期
 	"fmt"      // 用于格式化输出
 	"log"      // 用于日志记录
 	"net/http" // 提供HTTP客户端和服务器实现
+	"os"       // 用于读取命令行参数
 
 	// 导入内部包
+	"github.com/sulibao/knowledge/internal/auth"               // JWT会话签发/轮换的编排层，提供按权限位的RequireAuth中间件
 	"github.com/sulibao/knowledge/internal/config"             // 配置管理
 	"github.com/sulibao/knowledge/internal/database"           // 数据库操作
+	"github.com/sulibao/knowledge/internal/events"             // 桶事件监听与索引/去重/Webhook处理器
 	"github.com/sulibao/knowledge/internal/handlers"           // HTTP请求处理器
 	"github.com/sulibao/knowledge/internal/middleware"         // 中间件
 	minio_client "github.com/sulibao/knowledge/internal/minio" // MinIO客户端
@@ -29,13 +33,26 @@ func main() {
 	// 打印启动信息
 	fmt.Println("Starting knowledge base system...")
 
-	// 从./config.yaml中加载配置变量
-	// LoadConfig函数读取配置文件并解析为Config结构体
-	cfg, err := config.LoadConfig("./config.yaml")
+	// 解析命令行参数：-config指定配置文件路径，其余标志在"文件→环境变量"之上再叠加一层覆盖
+	configPath, overrides, err := config.ParseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error parsing command-line flags: %v", err)
+	}
+
+	// 加载配置（默认值→YAML文件→Profile覆盖文件→环境变量），并启动配置管理器监听后续的热重载
+	// config.Manager在LoadConfig的基础上监听文件变化（fsnotify）及SIGHUP信号，
+	// 重新加载后仅对发生变化的子配置（数据库/MinIO/HTTP服务）通知对应的订阅者；
+	// 命令行参数的覆盖则由Manager在每次加载后自动重新叠加一次，确保热重载不会丢失启动时的命令行覆盖
+	cfgManager, err := config.NewManager(configPath, overrides)
 	if err != nil {
 		// 如果配置加载失败，记录错误并终止程序
 		log.Fatalf("Error loading configuration: %v", err)
 	}
+	cfg := cfgManager.Snapshot()
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// 初始化PostgreSQL数据库连接，用于存储用户数据
 	// InitPostgres函数根据配置建立数据库连接
@@ -47,6 +64,10 @@ func main() {
 	// 确保在程序结束时关闭数据库连接
 	defer db.Close()
 
+	// dbPool包装原始连接，使各Store在数据库配置热重载时可以整体切换到新连接，而无需重启进程
+	dbPool := database.NewPool(db)
+	cfgManager.OnDatabaseChange(dbPool.Reload)
+
 	// 在PostgreSQL中创建必要的数据表（如用户表）
 	// CreateTables函数检查表是否存在，不存在则创建
 	err = database.CreateTables(db)
@@ -57,10 +78,26 @@ func main() {
 
 	// 创建用户存储服务并确保默认管理员用户存在
 	// NewUserStore函数创建一个用于操作用户数据的服务
-	userStore := database.NewUserStore(db)
+	userStore := database.NewUserStore(dbPool, cfg.PasswordHasher())
 	// EnsureDefaultAdmin函数确保系统中存在默认的admin用户
 	// 如果不存在则创建，如果存在则确保密码正确
-	userStore.EnsureDefaultAdmin()
+	userStore.EnsureDefaultAdmin(cfg.Security.AdminBootstrapPassword)
+
+	// 初始化JWT认证：根据配置的算法（HS256默认或RS256）加载密钥材料，注入供AuthRequired校验令牌使用
+	authKeys, err := middleware.LoadKeySet(cfg.JWT.Algorithm, cfg.JWT.Secret, cfg.JWT.PrivateKeyPath, cfg.JWT.PublicKeyPath, cfg.JWT.Issuer)
+	if err != nil {
+		log.Fatalf("Error loading JWT key material: %v", err)
+	}
+	middleware.InitAuth(authKeys, userStore)
+
+	// authService编排会话签发/轮换，并提供在Casbin路径级门禁之外、按models.Permission位掩码
+	// 做更细粒度校验的RequireAuth中间件，下面用于在管理员接口上额外要求manage_users能力位
+	authService := auth.NewService(userStore, authKeys, cfg.AccessTokenTTLDuration(), cfg.RefreshTokenTTLDuration())
+
+	// 初始化Casbin：基于casbin_rule表中的角色策略构建鉴权器
+	if err := middleware.InitCasbin(db); err != nil {
+		log.Fatalf("Error initializing Casbin enforcer: %v", err)
+	}
 
 	// 初始化MinIO对象存储服务及其存储桶
 	// InitMinio函数连接到MinIO服务并确保所需的存储桶存在
@@ -69,8 +106,28 @@ func main() {
 		// 如果MinIO初始化失败，记录错误并终止程序
 		log.Fatalf("Error initializing MinIO: %v", err)
 	}
-	// 临时使用变量以避免未使用变量的编译错误
-	_ = minioClient // 注：实际上下面的代码会使用这个变量
+	// minioPool包装原始客户端，使MinIO端点/凭证热重载后能够取到新的客户端；
+	// 下面各处理器均持有minioPool本身而非直接持有*minio.Client，每次操作都经Client()取用，
+	// 因此Reload替换的新客户端能被这些处理器感知到（桶通知的长连接本身不会被打断，最迟在
+	// listenLoop下一次重连时生效，见events.Listener的文档）
+	minioPool := minio_client.NewPool(minioClient)
+	cfgManager.OnMinioChange(minioPool.Reload)
+
+	// 创建文件索引存储，并启动桶事件监听：为对象创建/删除维护Postgres索引、计算sha256去重、投递Webhook
+	fileStore := database.NewFileStore(dbPool)
+	eventBus := events.NewDispatcher()
+	eventBus.Subscribe(events.NewIndexHandler(fileStore))
+	eventBus.Subscribe(events.NewChecksumHandler(minioPool, cfg.Minio.BucketName, fileStore, cfg, userStore))
+	eventBus.Subscribe(events.NewWebhookHandler(cfg.Events.WebhookURLs, cfg.Events.WebhookSecret))
+	eventBus.Subscribe(events.NewContentExtractionHandler(minioPool, cfg.Minio.BucketName, fileStore, cfg, userStore))
+
+	listener := events.NewListener(minioPool, eventBus, fileStore, cfg.Minio.BucketName)
+	listener.Start(context.Background(), cfg.ReconcileIntervalDuration())
+
+	// 启动配置热重载监听：文件变化或SIGHUP信号都会触发重新加载并通知上面注册的订阅者
+	if err := cfgManager.Start(context.Background()); err != nil {
+		log.Fatalf("Error starting configuration manager: %v", err)
+	}
 
 	// 创建HTTP路由器，用于处理所有HTTP请求
 	r := mux.NewRouter()
@@ -81,12 +138,14 @@ func main() {
 	r.PathPrefix("/public/").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("./public"))))
 
 	// 配置用户认证相关的路由
-	// 创建认证处理器，负责处理用户注册和登录
-	authHandler := handlers.NewAuthHandler(userStore)
+	// 创建认证处理器，负责处理用户注册、登录和令牌刷新
+	authHandler := handlers.NewAuthHandler(userStore, cfg, authKeys)
 	// 注册POST请求处理函数，处理用户注册
 	r.HandleFunc("/register", authHandler.Register).Methods("POST")
 	// 注册POST请求处理函数，处理用户登录
 	r.HandleFunc("/login", authHandler.Login).Methods("POST")
+	// 注册POST请求处理函数，使用刷新令牌换取新的访问令牌和刷新令牌
+	r.HandleFunc("/refresh", authHandler.Refresh).Methods("POST")
 
 	// 配置用户注册页面的路由
 	// 处理对/register路径的GET请求，返回注册页面
@@ -103,20 +162,67 @@ func main() {
 
 	// 配置文件管理相关的路由
 	// 创建文件处理器，负责处理文件上传、下载等操作
-	fileHandler := handlers.NewFileHandler(minioClient, cfg)
+	fileHandler := handlers.NewFileHandler(minioPool, cfg, userStore, fileStore)
 	// 创建需要认证的子路由器，所有/api前缀的请求都需要认证
 	protectedRouter := r.PathPrefix("/api").Subrouter()
-	// 使用认证中间件，确保只有已登录用户才能访问这些API
+	// 使用JWT认证中间件，确保只有携带有效访问令牌的请求才能进入/api/*
 	protectedRouter.Use(middleware.AuthRequired)
 
+	// 每个路由在JWT认证之外，还需通过Casbin按(资源路径, HTTP方法)校验角色权限
 	// 注册文件上传API，处理POST请求
-	protectedRouter.HandleFunc("/upload", fileHandler.UploadFile).Methods("POST")
+	protectedRouter.Handle("/upload", middleware.AuthorizeRequired("/api/upload", "POST")(http.HandlerFunc(fileHandler.UploadFile))).Methods("POST")
 	// 注册文件列表API，处理GET请求
-	protectedRouter.HandleFunc("/files", fileHandler.ListFiles).Methods("GET")
+	protectedRouter.Handle("/files", middleware.AuthorizeRequired("/api/files", "GET")(http.HandlerFunc(fileHandler.ListFiles))).Methods("GET")
 	// 注册文件下载API，处理GET请求
-	protectedRouter.HandleFunc("/download", fileHandler.DownloadFile).Methods("GET")
+	protectedRouter.Handle("/download", middleware.AuthorizeRequired("/api/download", "GET")(http.HandlerFunc(fileHandler.DownloadFile))).Methods("GET")
 	// 注册文件删除API，处理DELETE请求
-	protectedRouter.HandleFunc("/delete", fileHandler.DeleteFile).Methods("DELETE")
+	protectedRouter.Handle("/delete", middleware.AuthorizeRequired("/api/delete", "DELETE")(http.HandlerFunc(fileHandler.DeleteFile))).Methods("DELETE")
+
+	// 注册文件检索API，处理GET请求，支持按上传者/内容类型/大小/标签过滤及全文检索
+	protectedRouter.Handle("/search", middleware.AuthorizeRequired("/api/search", "GET")(http.HandlerFunc(fileHandler.Search))).Methods("GET")
+	// 注册文件标签更新API，处理PATCH请求，更新后的标签立即参与全文检索
+	protectedRouter.Handle("/files/{name}/tags", middleware.AuthorizeRequestPath("PATCH")(http.HandlerFunc(fileHandler.UpdateTags))).Methods("PATCH")
+
+	// 注册预签名上传URL申请API，处理POST请求
+	// 大文件场景下，仪表盘应优先使用该接口获取直传MinIO的URL，而不是走/upload中转
+	protectedRouter.Handle("/presign/upload", middleware.AuthorizeRequired("/api/presign/upload", "POST")(http.HandlerFunc(fileHandler.PresignUpload))).Methods("POST")
+	// 注册预签名下载URL申请API，处理GET请求
+	protectedRouter.Handle("/presign/download", middleware.AuthorizeRequired("/api/presign/download", "GET")(http.HandlerFunc(fileHandler.PresignDownload))).Methods("GET")
+
+	// 配置分片（可续传）上传相关的路由
+	// 创建分片上传处理器，负责大文件的初始化、分片传输、完成和中止
+	uploadStore := database.NewUploadStore(dbPool)
+	uploadHandler := handlers.NewUploadHandler(minioPool, cfg, uploadStore, userStore)
+	// 注册上传会话初始化API，处理POST请求
+	protectedRouter.Handle("/uploads", middleware.AuthorizeRequestPath("POST")(http.HandlerFunc(uploadHandler.InitUpload))).Methods("POST")
+	// 注册上传会话查询API，处理GET请求，用于断线重连后判断已上传的分片
+	protectedRouter.Handle("/uploads/{uploadId}", middleware.AuthorizeRequestPath("GET")(http.HandlerFunc(uploadHandler.GetUpload))).Methods("GET")
+	// 注册分片上传API，处理PUT请求
+	protectedRouter.Handle("/uploads/{uploadId}/parts/{partNumber}", middleware.AuthorizeRequestPath("PUT")(http.HandlerFunc(uploadHandler.UploadPart))).Methods("PUT")
+	// 注册上传完成API，处理POST请求
+	protectedRouter.Handle("/uploads/{uploadId}/complete", middleware.AuthorizeRequestPath("POST")(http.HandlerFunc(uploadHandler.CompleteUpload))).Methods("POST")
+	// 注册上传中止API，处理DELETE请求
+	protectedRouter.Handle("/uploads/{uploadId}", middleware.AuthorizeRequestPath("DELETE")(http.HandlerFunc(uploadHandler.AbortUpload))).Methods("DELETE")
+
+	// 配置管理员专属的用户与角色管理路由
+	// 创建管理处理器，负责用户列表查询和角色绑定调整
+	permissionStore := database.NewPermissionStore(dbPool)
+	adminHandler := handlers.NewAdminHandler(userStore, permissionStore, fileStore, minioPool, cfg)
+	// 管理员接口在Casbin的路径+角色门禁之外，再叠加一层authService.RequireAuth，
+	// 要求调用者的Permission位掩码包含manage_users位（admin角色默认具备，见models.DefaultPermission）
+	requireManageUsers := authService.RequireAuth(auth.ScopeManageUsers)
+	// 注册用户列表API，处理GET请求，仅admin角色可访问，支持分页与按用户名模糊匹配
+	protectedRouter.Handle("/admin/users", middleware.AuthorizeRequired("/api/admin/users", "GET")(requireManageUsers(http.HandlerFunc(adminHandler.ListUsers)))).Methods("GET")
+	// 注册用户删除API，处理DELETE请求，仅admin角色可访问
+	protectedRouter.Handle("/admin/users", middleware.AuthorizeRequired("/api/admin/users", "DELETE")(requireManageUsers(http.HandlerFunc(adminHandler.DeleteUser)))).Methods("DELETE")
+	// 注册角色绑定调整API，处理PATCH请求，仅admin角色可访问
+	protectedRouter.Handle("/admin/users/role", middleware.AuthorizeRequired("/api/admin/users/role", "PATCH")(requireManageUsers(http.HandlerFunc(adminHandler.UpdateUserRole)))).Methods("PATCH")
+	// 注册密码重置API，处理POST请求，仅admin角色可访问
+	protectedRouter.Handle("/admin/users/password", middleware.AuthorizeRequired("/api/admin/users/password", "POST")(requireManageUsers(http.HandlerFunc(adminHandler.ResetPassword)))).Methods("POST")
+	// 注册用户启用/禁用API，处理PATCH请求，仅admin角色可访问
+	protectedRouter.Handle("/admin/users/status", middleware.AuthorizeRequired("/api/admin/users/status", "PATCH")(requireManageUsers(http.HandlerFunc(adminHandler.SetUserStatus)))).Methods("PATCH")
+	// 注册密钥轮换API，处理POST请求，仅admin角色可访问，用于sse-c模式下更换用户的客户密钥
+	protectedRouter.Handle("/keys/rotate", middleware.AuthorizeRequired("/api/keys/rotate", "POST")(requireManageUsers(http.HandlerFunc(adminHandler.RotateKey)))).Methods("POST")
 
 	// 配置登录后的仪表盘页面路由
 	// 使用认证中间件包装处理函数，确保只有已登录用户才能访问仪表盘
@@ -127,12 +233,15 @@ func main() {
 
 	// 配置退出登录的路由
 	r.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
-		// 获取用户会话
-		session, _ := middleware.Store.Get(r, "session-name")
-		// 将认证状态设置为false，表示用户已退出登录
-		session.Values["authenticated"] = false
-		// 保存会话状态
-		session.Save(r, w)
+		// 清除存放访问令牌的cookie；客户端持有的刷新令牌应调用方自行丢弃
+		// （此处不吊销刷新令牌，服务也没有提供单独吊销刷新令牌的接口）
+		http.SetCookie(w, &http.Cookie{
+			Name:     "access_token",
+			Value:    "",
+			Path:     "/",
+			HttpOnly: true,
+			MaxAge:   -1,
+		})
 		// 重定向到登录页面
 		http.Redirect(w, r, "/login", http.StatusFound)
 	}).Methods("POST")